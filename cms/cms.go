@@ -0,0 +1,339 @@
+// Package cms parses and verifies CMS SignedData, RFC 5652, and its
+// PKCS#7 predecessor, RFC 2315, as found in signed archives whose
+// digest algorithm is RIPEMD-160 or Whirlpool.
+//
+// Digest algorithms are resolved by object identifier in package
+// legacy; register other hashes there, such as SHA-1, to verify
+// signatures made with them. Signatures are checked with package
+// x509legacy, which knows rsaEncryption with any digest as well as the
+// combined signature algorithms registered with it.
+//
+// Only the signatures are verified. To validate the certificate of a
+// signer, pass it to x509legacy.Verify with the signing time as
+// CurrentTime.
+//
+// Input must be DER encoded; BER with indefinite lengths, as written by
+// streaming signers, is not supported.
+package cms
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/y3sh/go-legacy-crypto/legacy"
+	"github.com/y3sh/go-legacy-crypto/x509legacy"
+)
+
+// Object identifiers of RFC 5652 and PKCS #9.
+var (
+	OIDData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	OIDSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	OIDMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	OIDSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+)
+
+var (
+	// ErrNoCertificate is returned for signers whose certificate is
+	// not known.
+	ErrNoCertificate = errors.New("cms: signer certificate not found")
+
+	// ErrVerification is returned, wrapped, for signatures and message
+	// digests that do not verify.
+	ErrVerification = errors.New("cms: verification error")
+)
+
+// contentInfo is both a ContentInfo and an EncapsulatedContentInfo.
+// Content holds the explicit [0] tag around the content.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo contentInfo
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []asn1.RawValue `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// SignedData is a parsed SignedData.
+type SignedData struct {
+	// ContentType is the type of the signed content, usually OIDData.
+	ContentType asn1.ObjectIdentifier
+
+	// Content is the signed content, nil for a detached signature.
+	// For content types other than data in PKCS#7, it is the DER
+	// encoding of the content.
+	Content []byte
+
+	// Certificates are the certificates shipped with the signature.
+	Certificates []*x509.Certificate
+
+	Signers []*SignerInfo
+}
+
+// SignerInfo describes one signer.
+type SignerInfo struct {
+	// The signer is identified either by the issuer and serial number of
+	// its certificate, or by its subject key identifier.
+	RawIssuer    []byte
+	SerialNumber *big.Int
+	SubjectKeyID []byte
+
+	// Certificate is the certificate of the signer among the
+	// certificates of the SignedData, nil if it is not there. It may be
+	// set before calling Verify.
+	Certificate *x509.Certificate
+
+	DigestAlgorithm    asn1.ObjectIdentifier
+	SignatureAlgorithm asn1.ObjectIdentifier
+	Signature          []byte
+
+	// Hash is the hash of DigestAlgorithm in package legacy, nil if it
+	// is not registered there.
+	Hash *legacy.Hash
+
+	// SigningTime is the value of the signing time attribute, zero if
+	// there is none.
+	SigningTime time.Time
+
+	// MessageDigest is the value of the message digest attribute, nil
+	// if the signature has no signed attributes.
+	MessageDigest []byte
+
+	// signedAttrs is the DER encoding of the signed attributes as a SET
+	// OF, the input of the signature.
+	signedAttrs []byte
+}
+
+// Parse parses a DER encoded ContentInfo holding a SignedData.
+func Parse(der []byte) (*SignedData, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("cms: malformed content info: %v", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("cms: trailing data after content info")
+	}
+	if !ci.ContentType.Equal(OIDSignedData) {
+		return nil, fmt.Errorf("cms: content type %v is not signed data", ci.ContentType)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("cms: malformed signed data: %v", err)
+	}
+	result := &SignedData{ContentType: sd.EncapContentInfo.ContentType}
+	if explicit := sd.EncapContentInfo.Content; len(explicit.Bytes) != 0 {
+		var content asn1.RawValue
+		if _, err := asn1.Unmarshal(explicit.Bytes, &content); err != nil {
+			return nil, fmt.Errorf("cms: malformed content: %v", err)
+		}
+		result.Content = content.FullBytes
+		if content.Class == asn1.ClassUniversal && content.Tag == asn1.TagOctetString && !content.IsCompound {
+			result.Content = content.Bytes
+		}
+	}
+	if len(sd.Certificates.Bytes) != 0 {
+		certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cms: %v", err)
+		}
+		result.Certificates = certs
+	}
+	for _, raw := range sd.SignerInfos {
+		si, err := parseSignerInfo(raw.FullBytes, result.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		si.Certificate = findCertificate(si, result.Certificates)
+		result.Signers = append(result.Signers, si)
+	}
+	if len(result.Signers) == 0 {
+		return nil, errors.New("cms: no signer")
+	}
+	return result, nil
+}
+
+func parseSignerInfo(der []byte, contentType asn1.ObjectIdentifier) (*SignerInfo, error) {
+	var info signerInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("cms: malformed signer info: %v", err)
+	}
+	si := &SignerInfo{
+		DigestAlgorithm:    info.DigestAlgorithm.Algorithm,
+		SignatureAlgorithm: info.SignatureAlgorithm.Algorithm,
+		Signature:          info.Signature,
+	}
+	si.Hash, _ = legacy.LookupOID(si.DigestAlgorithm)
+	switch sid := info.SID; {
+	case sid.Class == asn1.ClassContextSpecific && sid.Tag == 0:
+		si.SubjectKeyID = sid.Bytes
+	case sid.Class == asn1.ClassUniversal && sid.Tag == asn1.TagSequence:
+		var ias issuerAndSerialNumber
+		if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+			return nil, fmt.Errorf("cms: malformed signer identifier: %v", err)
+		}
+		si.RawIssuer, si.SerialNumber = ias.Issuer.FullBytes, ias.SerialNumber
+	default:
+		return nil, errors.New("cms: malformed signer identifier")
+	}
+	if len(info.SignedAttrs.FullBytes) != 0 {
+		if err := si.parseSignedAttrs(info.SignedAttrs, contentType); err != nil {
+			return nil, err
+		}
+	}
+	return si, nil
+}
+
+// parseSignedAttrs reads the signed attributes, which must include the
+// content type and the message digest.
+func (si *SignerInfo) parseSignedAttrs(attrs asn1.RawValue, contentType asn1.ObjectIdentifier) error {
+	// the signature covers the attributes with their SET OF tag, not
+	// the implicit [0] tag they are stored with.
+	si.signedAttrs = append([]byte{0x31}, attrs.FullBytes[1:]...)
+	var hasContentType bool
+	for rest := attrs.Bytes; len(rest) > 0; {
+		var attr attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return fmt.Errorf("cms: malformed signed attribute: %v", err)
+		}
+		value := attr.Values.Bytes
+		switch {
+		case attr.Type.Equal(OIDContentType):
+			var oid asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(value, &oid); err != nil {
+				return fmt.Errorf("cms: malformed content type attribute: %v", err)
+			}
+			if !oid.Equal(contentType) {
+				return fmt.Errorf("cms: content type attribute %v, content of type %v", oid, contentType)
+			}
+			hasContentType = true
+		case attr.Type.Equal(OIDMessageDigest):
+			if _, err := asn1.Unmarshal(value, &si.MessageDigest); err != nil {
+				return fmt.Errorf("cms: malformed message digest attribute: %v", err)
+			}
+		case attr.Type.Equal(OIDSigningTime):
+			if _, err := asn1.Unmarshal(value, &si.SigningTime); err != nil {
+				return fmt.Errorf("cms: malformed signing time attribute: %v", err)
+			}
+		}
+	}
+	if !hasContentType || si.MessageDigest == nil {
+		return errors.New("cms: signed attributes without content type or message digest")
+	}
+	return nil
+}
+
+func findCertificate(si *SignerInfo, certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs {
+		if si.SubjectKeyID != nil {
+			if bytes.Equal(cert.SubjectKeyId, si.SubjectKeyID) {
+				return cert
+			}
+		} else if bytes.Equal(cert.RawIssuer, si.RawIssuer) && cert.SerialNumber.Cmp(si.SerialNumber) == 0 {
+			return cert
+		}
+	}
+	return nil
+}
+
+// Verify checks the signature of every signer over the content, which
+// is detached if not nil and sd.Content otherwise, and returns the
+// signers.
+func (sd *SignedData) Verify(detached []byte) ([]*SignerInfo, error) {
+	content := sd.Content
+	if detached != nil {
+		if content != nil {
+			return nil, errors.New("cms: detached content given for attached signature")
+		}
+		content = detached
+	}
+	if content == nil {
+		return nil, errors.New("cms: no content to verify")
+	}
+	for _, si := range sd.Signers {
+		if err := si.verify(content); err != nil {
+			return nil, err
+		}
+	}
+	return sd.Signers, nil
+}
+
+func (si *SignerInfo) verify(content []byte) error {
+	if si.Certificate == nil {
+		return ErrNoCertificate
+	}
+	if si.Hash == nil {
+		return fmt.Errorf("cms: digest algorithm: %w %v", legacy.ErrUnknown, si.DigestAlgorithm)
+	}
+	alg, err := si.signatureAlgorithm()
+	if err != nil {
+		return err
+	}
+	signed := content
+	if si.signedAttrs != nil {
+		h := si.Hash.New()
+		h.Write(content)
+		if subtle.ConstantTimeCompare(h.Sum(nil), si.MessageDigest) != 1 {
+			return fmt.Errorf("%w: message digest of %s does not match", ErrVerification, si.Certificate.Subject)
+		}
+		signed = si.signedAttrs
+	}
+	if err := x509legacy.CheckSignature(alg, si.Certificate.PublicKey, signed, si.Signature); err != nil {
+		return fmt.Errorf("%w: signature of %s: %v", ErrVerification, si.Certificate.Subject, err)
+	}
+	return nil
+}
+
+// signatureAlgorithm resolves the signature algorithm of si, which must
+// agree with its digest algorithm.
+func (si *SignerInfo) signatureAlgorithm() (*x509legacy.Algorithm, error) {
+	if si.SignatureAlgorithm.Equal(oidRSAEncryption) {
+		return &x509legacy.Algorithm{
+			Name:      "rsaEncryption",
+			OID:       oidRSAEncryption,
+			PublicKey: x509.RSA,
+			Hash:      si.Hash,
+		}, nil
+	}
+	alg, err := x509legacy.LookupAlgorithm(si.SignatureAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("cms: %w", err)
+	}
+	if alg.Hash.Name != si.Hash.Name {
+		return nil, fmt.Errorf("cms: signature algorithm %s with digest algorithm %s", alg.Name, si.Hash.Name)
+	}
+	return alg, nil
+}
@@ -0,0 +1,265 @@
+package cms
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/y3sh/go-legacy-crypto/legacy"
+	"github.com/y3sh/go-legacy-crypto/pkcs1"
+)
+
+// The signatures in testdata were made with OpenSSL 3 over doc.txt,
+// with the leaf certificate of the x509legacy tests:
+//
+//	openssl cms -sign -binary -md ripemd160 -nodetach   attached.p7s
+//	openssl cms -sign -binary -md ripemd160             detached.p7s
+//	... -noattr -nodetach                               noattr.p7s
+//	... -keyid -nodetach                                keyid.p7s
+//	openssl cms -sign -binary -md sha1 -nodetach        sha1.p7s
+//
+// OpenSSL refuses Whirlpool with RSA, so those are made by sign below,
+// with root.key and root.pem.
+
+func readFile(t *testing.T, name string) []byte {
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func parseFile(t *testing.T, name string) *SignedData {
+	sd, err := Parse(readFile(t, name))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return sd
+}
+
+func TestVerifyOpenSSL(t *testing.T) {
+	doc := readFile(t, "doc.txt")
+	for _, name := range []string{"attached.p7s", "detached.p7s", "noattr.p7s", "keyid.p7s"} {
+		sd := parseFile(t, name)
+		detached := name == "detached.p7s"
+		if detached != (sd.Content == nil) || !detached && !bytes.Equal(sd.Content, doc) {
+			t.Errorf("%s: Expected content %q, got %q", name, doc, sd.Content)
+		}
+		var content []byte
+		if detached {
+			content = doc
+		}
+		signers, err := sd.Verify(content)
+		if err != nil {
+			t.Errorf("%s: Expected no error, got %v", name, err)
+			continue
+		}
+		if len(signers) != 1 || signers[0].Certificate.Subject.CommonName != "Document Signer" {
+			t.Errorf("%s: Expected the Document Signer, got %v", name, signers)
+		}
+		if signers[0].Hash.Name != "RIPEMD-160" {
+			t.Errorf("%s: Expected RIPEMD-160, got %s", name, signers[0].Hash.Name)
+		}
+		if signed := name != "noattr.p7s"; signed == signers[0].SigningTime.IsZero() {
+			t.Errorf("%s: Expected a signing time %v, got %v", name, signed, signers[0].SigningTime)
+		}
+	}
+
+	si := parseFile(t, "attached.p7s").Signers[0]
+	if expected := time.Date(2026, 10, 17, 18, 44, 53, 0, time.UTC); !si.SigningTime.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, si.SigningTime)
+	}
+	if expected := "ec19c556de67ac0ee7e933f6a2b1c65ba56747cc"; hex.EncodeToString(si.MessageDigest) != expected {
+		t.Errorf("Expected %v, got %x", expected, si.MessageDigest)
+	}
+	if si.SerialNumber.Int64() != 3 || si.SubjectKeyID != nil {
+		t.Errorf("Expected serial number 3, got %v", si.SerialNumber)
+	}
+	if si = parseFile(t, "keyid.p7s").Signers[0]; !bytes.Equal(si.SubjectKeyID, si.Certificate.SubjectKeyId) {
+		t.Errorf("Expected %x, got %x", si.Certificate.SubjectKeyId, si.SubjectKeyID)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	doc := readFile(t, "doc.txt")
+	for _, name := range []string{"attached.p7s", "noattr.p7s"} {
+		sd := parseFile(t, name)
+		sd.Content = append([]byte(nil), sd.Content...)
+		sd.Content[0] ^= 1
+		if _, err := sd.Verify(nil); !errors.Is(err, ErrVerification) {
+			t.Errorf("%s: Expected ErrVerification, got %v", name, err)
+		}
+	}
+
+	sd := parseFile(t, "detached.p7s")
+	if _, err := sd.Verify(nil); err == nil {
+		t.Errorf("Expected an error without the detached content")
+	}
+	if _, err := sd.Verify(append(doc, '.')); !errors.Is(err, ErrVerification) {
+		t.Errorf("Expected ErrVerification, got %v", err)
+	}
+
+	sd = parseFile(t, "attached.p7s")
+	if _, err := sd.Verify(doc); err == nil {
+		t.Errorf("Expected an error for detached content given to an attached signature")
+	}
+	cert := sd.Signers[0].Certificate
+	sd.Signers[0].Certificate = nil
+	if _, err := sd.Verify(nil); !errors.Is(err, ErrNoCertificate) {
+		t.Errorf("Expected ErrNoCertificate, got %v", err)
+	}
+	sd.Signers[0].Certificate = cert
+	if _, err := sd.Verify(nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestVerifyUnregisteredDigest(t *testing.T) {
+	sd := parseFile(t, "sha1.p7s")
+	if _, err := sd.Verify(nil); !errors.Is(err, legacy.ErrUnknown) {
+		t.Fatalf("Expected legacy.ErrUnknown, got %v", err)
+	}
+	h := &legacy.Hash{
+		Name:      "SHA-1",
+		OIDs:      []asn1.ObjectIdentifier{sd.Signers[0].DigestAlgorithm},
+		Size:      sha1.Size,
+		BlockSize: sha1.BlockSize,
+		New:       sha1.New,
+	}
+	if err := legacy.Register(h); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { legacy.Unregister(h) })
+	if _, err := parseFile(t, "sha1.p7s").Verify(nil); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func loadRoot(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	block, _ := pem.Decode(readFile(t, "root.pem"))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode(readFile(t, "root.key"))
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key.(*rsa.PrivateKey)
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// explicit wraps der in a context-specific constructed tag.
+func explicit(tag int, der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: der}
+}
+
+// sign returns a SignedData over content signed by cert and key with
+// signed attributes, hashing with h.
+func sign(t *testing.T, content []byte, detached bool, h *legacy.Hash, cert *x509.Certificate, key *rsa.PrivateKey, signingTime time.Time) []byte {
+	d := h.New()
+	d.Write(content)
+	var attrs []byte
+	for _, attr := range []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{OIDContentType, OIDData},
+		{OIDSigningTime, signingTime},
+		{OIDMessageDigest, d.Sum(nil)},
+	} {
+		values := asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: mustMarshal(t, attr.value)}
+		attrs = append(attrs, mustMarshal(t, attribute{attr.oid, values})...)
+	}
+	d.Reset()
+	d.Write(mustMarshal(t, asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attrs}))
+	sig, err := pkcs1.Sign(rand.Reader, key, h, d.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: h.OIDs[0], Parameters: asn1.NullRawValue}
+	si := signerInfo{
+		Version: 1,
+		SID: asn1.RawValue{FullBytes: mustMarshal(t, issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+			SerialNumber: cert.SerialNumber,
+		})},
+		DigestAlgorithm:    digestAlg,
+		SignedAttrs:        explicit(0, attrs),
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue},
+		Signature:          sig,
+	}
+	encap := contentInfo{ContentType: OIDData}
+	if !detached {
+		encap.Content = explicit(0, mustMarshal(t, content))
+	}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlg},
+		EncapContentInfo: encap,
+		Certificates:     explicit(0, cert.Raw),
+		SignerInfos:      []asn1.RawValue{{FullBytes: mustMarshal(t, si)}},
+	}
+	return mustMarshal(t, contentInfo{OIDSignedData, explicit(0, mustMarshal(t, sd))})
+}
+
+func TestVerifyWhirlpool(t *testing.T) {
+	cert, key := loadRoot(t)
+	wp, _ := legacy.Lookup("Whirlpool")
+	content := []byte("Signed with Whirlpool")
+	signingTime := time.Date(2006, 3, 14, 9, 26, 53, 0, time.UTC)
+	for _, detached := range []bool{false, true} {
+		sd, err := Parse(sign(t, content, detached, wp, cert, key, signingTime))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var external []byte
+		if detached {
+			external = content
+		} else if !bytes.Equal(sd.Content, content) {
+			t.Errorf("Expected %q, got %q", content, sd.Content)
+		}
+		signers, err := sd.Verify(external)
+		if err != nil {
+			t.Fatalf("Detached %v: Expected no error, got %v", detached, err)
+		}
+		si := signers[0]
+		if si.Hash != wp || si.Certificate != sd.Certificates[0] || !si.SigningTime.Equal(signingTime) {
+			t.Errorf("Detached %v: Expected Whirlpool by %s at %v, got %s by %v at %v", detached,
+				cert.Subject, signingTime, si.Hash.Name, si.Certificate, si.SigningTime)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	data := mustMarshal(t, contentInfo{OIDData, explicit(0, mustMarshal(t, []byte("data")))})
+	if _, err := Parse(data); err == nil {
+		t.Errorf("Expected an error for data content")
+	}
+	signed := readFile(t, "attached.p7s")
+	if _, err := Parse(append(signed, 0)); err == nil {
+		t.Errorf("Expected an error for trailing data")
+	}
+	if _, err := Parse(signed[:len(signed)-1]); err == nil {
+		t.Errorf("Expected an error for truncated data")
+	}
+}
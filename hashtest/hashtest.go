@@ -0,0 +1,199 @@
+// Package hashtest checks that a hash.Hash implementation behaves the
+// way the hash.Hash documentation promises, independently of the digest
+// it computes. It is meant to be called from the tests of each hash
+// package:
+//
+//	func TestConformance(t *testing.T) {
+//		hashtest.TestHash(t, ripemd320.New)
+//	}
+package hashtest
+
+import (
+	"bytes"
+	"hash"
+	"math/rand"
+	"testing"
+)
+
+// TestHash runs every check of the package against the hashes returned
+// by mh, which must return a fresh hash.Hash on each call.
+func TestHash(t *testing.T, mh func() hash.Hash) {
+	t.Run("SizeBlockSize", func(t *testing.T) { TestSizeBlockSize(t, mh) })
+	t.Run("SumAppend", func(t *testing.T) { TestSumAppend(t, mh) })
+	t.Run("SumTwice", func(t *testing.T) { TestSumTwice(t, mh) })
+	t.Run("Reset", func(t *testing.T) { TestReset(t, mh) })
+	t.Run("WriteSplits", func(t *testing.T) { TestWriteSplits(t, mh) })
+	t.Run("RandomSplits", func(t *testing.T) { TestRandomSplits(t, mh) })
+	t.Run("WriteDoesNotModify", func(t *testing.T) { TestWriteDoesNotModify(t, mh) })
+	t.Run("Allocations", func(t *testing.T) { TestAllocations(t, mh) })
+}
+
+// message returns n pseudo-random bytes, the same ones on every call.
+func message(n int) []byte {
+	msg := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(msg)
+	return msg
+}
+
+// oneShot returns the checksum of msg written with a single call.
+func oneShot(mh func() hash.Hash, msg []byte) []byte {
+	h := mh()
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// TestSizeBlockSize checks that Size matches the length of Sum and that
+// both Size and BlockSize are positive and stable.
+func TestSizeBlockSize(t *testing.T, mh func() hash.Hash) {
+	h := mh()
+	size, blockSize := h.Size(), h.BlockSize()
+	if size <= 0 || blockSize <= 0 {
+		t.Fatalf("Expected positive sizes, got Size %d and BlockSize %d", size, blockSize)
+	}
+	if sum := h.Sum(nil); len(sum) != size {
+		t.Errorf("Expected Sum of length %d, got %d", size, len(sum))
+	}
+	h.Write(message(3 * blockSize))
+	if h.Size() != size || h.BlockSize() != blockSize {
+		t.Errorf("Expected Size %d and BlockSize %d after Write, got %d and %d",
+			size, blockSize, h.Size(), h.BlockSize())
+	}
+}
+
+// TestSumAppend checks that Sum appends to its argument, leaving the
+// existing bytes alone, both with and without spare capacity.
+func TestSumAppend(t *testing.T, mh func() hash.Hash) {
+	h := mh()
+	h.Write(message(100))
+	sum := h.Sum(nil)
+	prefix := []byte("prefix")
+	for _, extra := range []int{0, 1, len(sum)} {
+		in := make([]byte, len(prefix), len(prefix)+extra)
+		copy(in, prefix)
+		out := h.Sum(in)
+		if !bytes.Equal(out[:len(prefix)], prefix) {
+			t.Errorf("Expected Sum to keep the prefix %x, got %x", prefix, out[:len(prefix)])
+		}
+		if !bytes.Equal(out[len(prefix):], sum) {
+			t.Errorf("Expected appended checksum %x, got %x", sum, out[len(prefix):])
+		}
+	}
+}
+
+// TestSumTwice checks that Sum does not change the underlying state, so
+// that it returns the same checksum twice and writing can continue.
+func TestSumTwice(t *testing.T, mh func() hash.Hash) {
+	msg := message(2*mh().BlockSize() + 7)
+	h := mh()
+	h.Write(msg[:10])
+	first, second := h.Sum(nil), h.Sum(nil)
+	if !bytes.Equal(first, second) {
+		t.Errorf("Expected repeated Sum %x, got %x", first, second)
+	}
+	h.Write(msg[10:])
+	if expected, actual := oneShot(mh, msg), h.Sum(nil); !bytes.Equal(expected, actual) {
+		t.Errorf("Expected writing after Sum to give %x, got %x", expected, actual)
+	}
+}
+
+// TestReset checks that Reset returns the hash to its initial state.
+func TestReset(t *testing.T, mh func() hash.Hash) {
+	empty := mh().Sum(nil)
+	h := mh()
+	h.Write(message(3*h.BlockSize() + 1))
+	h.Reset()
+	if actual := h.Sum(nil); !bytes.Equal(empty, actual) {
+		t.Errorf("Expected the empty checksum %x after Reset, got %x", empty, actual)
+	}
+	msg := message(100)
+	h.Write(msg)
+	if expected, actual := oneShot(mh, msg), h.Sum(nil); !bytes.Equal(expected, actual) {
+		t.Errorf("Expected writing after Reset to give %x, got %x", expected, actual)
+	}
+}
+
+// TestWriteSplits checks that splitting a message of a few blocks in two
+// at every possible boundary, and in three around every block boundary,
+// does not change the checksum.
+func TestWriteSplits(t *testing.T, mh func() hash.Hash) {
+	blockSize := mh().BlockSize()
+	msg := message(3*blockSize + 5)
+	expected := oneShot(mh, msg)
+	h := mh()
+	for i := 0; i <= len(msg); i++ {
+		h.Reset()
+		h.Write(msg[:i])
+		h.Write(msg[i:])
+		if actual := h.Sum(nil); !bytes.Equal(expected, actual) {
+			t.Fatalf("Split at %d: Expected %x, got %x", i, expected, actual)
+		}
+	}
+	for i := 0; i <= len(msg); i++ {
+		for j := blockSize - 1; j <= blockSize+1; j++ {
+			if i+j > len(msg) {
+				continue
+			}
+			h.Reset()
+			h.Write(msg[:i])
+			h.Write(msg[i : i+j])
+			h.Write(msg[i+j:])
+			if actual := h.Sum(nil); !bytes.Equal(expected, actual) {
+				t.Fatalf("Split at %d and %d: Expected %x, got %x", i, i+j, expected, actual)
+			}
+		}
+	}
+}
+
+// TestRandomSplits compares the checksums of random messages written in
+// random pieces, including empty ones, to their one-shot checksums.
+func TestRandomSplits(t *testing.T, mh func() hash.Hash) {
+	rng := rand.New(rand.NewSource(1))
+	h := mh()
+	for n := 0; n < 100; n++ {
+		msg := make([]byte, rng.Intn(10*h.BlockSize()))
+		rng.Read(msg)
+		expected := oneShot(mh, msg)
+		h.Reset()
+		for rest := msg; len(rest) > 0; {
+			k := rng.Intn(min(len(rest), 2*h.BlockSize()) + 1)
+			h.Write(rest[:k])
+			rest = rest[k:]
+		}
+		if actual := h.Sum(nil); !bytes.Equal(expected, actual) {
+			t.Fatalf("Message of %d bytes: Expected %x, got %x", len(msg), expected, actual)
+		}
+	}
+}
+
+// TestWriteDoesNotModify checks that neither Write nor Sum change the
+// bytes they are given.
+func TestWriteDoesNotModify(t *testing.T, mh func() hash.Hash) {
+	msg := message(2*mh().BlockSize() + 3)
+	saved := bytes.Clone(msg)
+	h := mh()
+	h.Write(msg)
+	if !bytes.Equal(msg, saved) {
+		t.Errorf("Expected Write to leave its input alone")
+	}
+	in := bytes.Clone(msg)
+	h.Sum(in[:0])
+	if !bytes.Equal(in[h.Size():], saved[h.Size():]) {
+		t.Errorf("Expected Sum to write only the checksum")
+	}
+}
+
+// TestAllocations checks that Reset, Write and Sum into a buffer with
+// enough capacity do not allocate.
+func TestAllocations(t *testing.T, mh func() hash.Hash) {
+	h := mh()
+	msg := message(5*h.BlockSize() + 3)
+	out := make([]byte, 0, h.Size())
+	n := testing.AllocsPerRun(10, func() {
+		h.Reset()
+		h.Write(msg)
+		out = h.Sum(out[:0])
+	})
+	if n > 0 {
+		t.Errorf("Expected 0 allocations, got %v", n)
+	}
+}
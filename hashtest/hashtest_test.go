@@ -0,0 +1,80 @@
+package hashtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func TestSHA256(t *testing.T) {
+	TestHash(t, sha256.New)
+}
+
+const nessieSample = `Primitive Name: SHA-256
+========================
+Hash size: 256 bits
+
+Test vectors -- set 1
+=====================
+
+Set 1, vector#  0:
+                       message="" (empty string)
+                          hash=E3B0C44298FC1C149AFBF4C8996FB924
+                               27AE41E4649B934CA495991B7852B855
+
+Set 1, vector#  7:
+                       message=8 times "1234567890"
+                          hash=F371BC4A311F2B009EEF952DD83CA80E
+                               2B60026C8E935592D0F9C308453C813E
+
+Test vectors -- set 3
+=====================
+
+Set 3, vector#  0:
+                       message=16-bit string: 8000
+                          hash=01
+
+Set 4, vector#  0:
+                       message=7 zero bits
+                          hash=00
+                   iterated 3 times=0102
+`
+
+func TestParseNESSIE(t *testing.T) {
+	vectors, err := ParseNESSIE(strings.NewReader(nessieSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 4 {
+		t.Fatalf("Expected 4 vectors, got %d", len(vectors))
+	}
+	if v := vectors[0]; v.Set != 1 || v.Index != 0 || len(v.Message) != 0 || v.Bits != 0 || len(v.Hash) != sha256.Size {
+		t.Errorf("Expected the empty message, got %+v", v)
+	}
+	if v := vectors[1]; v.Index != 7 || string(v.Message) != strings.Repeat("1234567890", 8) || v.Bits != 640 {
+		t.Errorf("Expected a repeated message, got %+v", v)
+	}
+	if sum := sha256.Sum256(vectors[1].Message); !bytes.Equal(sum[:], vectors[1].Hash) {
+		t.Errorf("Expected hash %X, got %X", sum, vectors[1].Hash)
+	}
+	if v := vectors[2]; v.Set != 3 || !bytes.Equal(v.Message, []byte{0x80, 0}) || v.Bits != 16 {
+		t.Errorf("Expected a bit string, got %+v", v)
+	}
+	if v := vectors[3]; !bytes.Equal(v.Message, []byte{0}) || v.Bits != 7 || v.Iterations != 3 || !bytes.Equal(v.IteratedHash, []byte{1, 2}) {
+		t.Errorf("Expected an iterated vector, got %+v", v)
+	}
+}
+
+func TestParseNESSIEErrors(t *testing.T) {
+	for _, sample := range []string{
+		"Set 1, vector#  0:\n    hash=00\n",
+		"Set 1, vector#  0:\n    message=\"a\"\n    hash=XY\n",
+		"Set 1, vector#  0:\n    message=a few bits\n    hash=00\n",
+		"Set 1, vector#  0:\n    message=16-bit string: 80\n    hash=00\n",
+	} {
+		if _, err := ParseNESSIE(strings.NewReader(sample)); err == nil {
+			t.Errorf("Expected an error for %q", sample)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package hashtest
+
+// Reading and running the test vector files of the NESSIE project,
+// which list for each set the message in words and its hash in hex:
+//
+//	Set 1, vector#  8:
+//	                       message=1 million times "a"
+//	                          hash=52783243C1697BDBE16D37F97F68F083
+//	                               25DC1528
+//
+// The sets are
+//
+//	set 1: strings of characters, the message in quotes
+//	set 2: messages of n zero bits
+//	set 3: 512-bit strings with a single bit set, the message in hex
+//	set 4: a message hashed repeatedly, with an "iterated n times" line
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A Vector is one test vector of a NESSIE hash vector file.
+type Vector struct {
+	Set, Index int
+
+	// Message holds the Bits bits of the message, most significant
+	// bit first, the last byte padded with zero bits.
+	Message []byte
+	Bits    uint64
+
+	Hash []byte
+
+	// For the vectors of set 4, IteratedHash is the result of hashing
+	// Message, then hashing the previous hash, Iterations times in
+	// all. Iterations is zero for the other vectors.
+	Iterations   int
+	IteratedHash []byte
+}
+
+// A BitWriter is a hash.Hash accepting messages of any bit length, like
+// whirlpool.Hash.
+type BitWriter interface {
+	hash.Hash
+	WriteBits(data []byte, nbits uint64)
+}
+
+var (
+	vectorHeader = regexp.MustCompile(`^Set (\d+), vector#\s*(\d+):$`)
+	quoted       = regexp.MustCompile(`^"(.*)"(?: \(empty string\))?$`)
+	repeated     = regexp.MustCompile(`^(\d+)( million)? times "(.*)"$`)
+	zeroBits     = regexp.MustCompile(`^(\d+) zero bits$`)
+	bitString    = regexp.MustCompile(`^(\d+)-bit string: ([0-9A-Fa-f]+)$`)
+	iterated     = regexp.MustCompile(`^iterated (\d+) times$`)
+)
+
+// ParseNESSIE reads the vectors of a NESSIE hash vector file. Lines
+// outside of vectors, such as the file and set headers, are ignored.
+func ParseNESSIE(r io.Reader) ([]Vector, error) {
+	var vectors []Vector
+	var fields map[string]string
+	var key string
+	var set, index, headerLine int
+	flush := func() error {
+		if fields == nil {
+			return nil
+		}
+		v, err := newVector(set, index, fields)
+		if err != nil {
+			return fmt.Errorf("hashtest: vector at line %d: %v", headerLine, err)
+		}
+		vectors = append(vectors, v)
+		fields = nil
+		return nil
+	}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case vectorHeader.MatchString(text):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			m := vectorHeader.FindStringSubmatch(text)
+			set, _ = strconv.Atoi(m[1])
+			index, _ = strconv.Atoi(m[2])
+			fields, key, headerLine = map[string]string{}, "", line
+		case fields == nil:
+			// outside of a vector.
+		case trimmed == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case text == trimmed:
+			// an unindented line ends the vector.
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case strings.Contains(trimmed, "="):
+			k, v, _ := strings.Cut(trimmed, "=")
+			key = strings.TrimSpace(k)
+			fields[key] = v
+		case key != "":
+			// continuation of a long value.
+			fields[key] += trimmed
+		default:
+			return nil, fmt.Errorf("hashtest: line %d: unexpected %q", line, trimmed)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func newVector(set, index int, fields map[string]string) (Vector, error) {
+	v := Vector{Set: set, Index: index}
+	message, ok := fields["message"]
+	if !ok {
+		return v, fmt.Errorf("no message")
+	}
+	if err := v.parseMessage(message); err != nil {
+		return v, err
+	}
+	var err error
+	if v.Hash, err = hex.DecodeString(fields["hash"]); err != nil || len(v.Hash) == 0 {
+		return v, fmt.Errorf("bad hash %q", fields["hash"])
+	}
+	for k, value := range fields {
+		m := iterated.FindStringSubmatch(k)
+		if m == nil {
+			continue
+		}
+		v.Iterations, _ = strconv.Atoi(m[1])
+		if v.IteratedHash, err = hex.DecodeString(value); err != nil || len(v.IteratedHash) == 0 {
+			return v, fmt.Errorf("bad iterated hash %q", value)
+		}
+	}
+	return v, nil
+}
+
+func (v *Vector) parseMessage(s string) error {
+	if m := quoted.FindStringSubmatch(s); m != nil {
+		v.Message = []byte(m[1])
+	} else if m := repeated.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if m[2] != "" {
+			n *= 1000000
+		}
+		v.Message = bytes.Repeat([]byte(m[3]), n)
+	} else if m := zeroBits.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.ParseUint(m[1], 10, 32)
+		v.Message = make([]byte, (n+7)/8)
+		v.Bits = n
+		return nil
+	} else if m := bitString.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.ParseUint(m[1], 10, 32)
+		msg, err := hex.DecodeString(m[2])
+		if err != nil || uint64(len(msg)) != (n+7)/8 {
+			return fmt.Errorf("bad bit string %q", s)
+		}
+		v.Message = msg
+		v.Bits = n
+		return nil
+	} else {
+		return fmt.Errorf("unknown message %q", s)
+	}
+	v.Bits = 8 * uint64(len(v.Message))
+	return nil
+}
+
+// TestNESSIE runs every vector of the NESSIE hash vector file at path
+// against the hashes returned by mh. Vectors whose length is not a
+// whole number of bytes are skipped unless the hashes are BitWriters.
+func TestNESSIE(t *testing.T, mh func() hash.Hash, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	vectors, err := ParseNESSIE(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) == 0 {
+		t.Fatalf("Expected vectors in %s", path)
+	}
+	h := mh()
+	bw, bitGranular := h.(BitWriter)
+	skipped := 0
+	for _, v := range vectors {
+		h.Reset()
+		switch {
+		case v.Bits%8 == 0:
+			h.Write(v.Message[:v.Bits/8])
+		case bitGranular:
+			bw.WriteBits(v.Message, v.Bits)
+		default:
+			skipped++
+			continue
+		}
+		sum := h.Sum(nil)
+		if !bytes.Equal(sum, v.Hash) {
+			t.Errorf("Set %d, vector#%d: Expected %X, got %X", v.Set, v.Index, v.Hash, sum)
+			continue
+		}
+		if v.Iterations == 0 {
+			continue
+		}
+		for i := 1; i < v.Iterations; i++ {
+			h.Reset()
+			h.Write(sum)
+			sum = h.Sum(sum[:0])
+		}
+		if !bytes.Equal(sum, v.IteratedHash) {
+			t.Errorf("Set %d, vector#%d iterated %d times: Expected %X, got %X",
+				v.Set, v.Index, v.Iterations, v.IteratedHash, sum)
+		}
+	}
+	if skipped > 0 {
+		t.Logf("skipped %d of %d vectors not made of whole bytes", skipped, len(vectors))
+	}
+}
@@ -0,0 +1,182 @@
+package kdf
+
+//  PBKDF2 key derivation over the legacy hashes of this module.
+//
+//  PBKDF2 is specified in PKCS #5 v2.1:
+//    K. Moriarty, B. Kaliski, A. Rusch,
+//    ``PKCS #5: Password-Based Cryptography Specification Version 2.1,''
+//    RFC 8018, 2017, https://www.rfc-editor.org/rfc/rfc8018
+//
+//  Verifiers are stored in the PHC string format:
+//    https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"github.com/y3sh/go-legacy-crypto/ripemd160"
+	"github.com/y3sh/go-legacy-crypto/ripemd320"
+	"github.com/y3sh/go-legacy-crypto/whirlpool"
+)
+
+// Key derives a key of keyLen bytes from password and salt with PBKDF2,
+// using HMAC over h as the pseudo-random function and iter iterations.
+// An iter below 1 counts as 1 and keyLen must not be negative. Key does
+// not bound iter; check counts that come from untrusted input.
+func Key(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	if keyLen < 0 {
+		panic("kdf: negative key length")
+	}
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		// U_1 = PRF(password, salt || INT(block))
+		prf.Reset()
+		prf.Write(salt)
+		buf[0] = byte(block >> 24)
+		buf[1] = byte(block >> 16)
+		buf[2] = byte(block >> 8)
+		buf[3] = byte(block)
+		prf.Write(buf[:4])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		// U_n = PRF(password, U_(n-1)), T = U_1 ^ ... ^ U_iter
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = u[:0]
+			u = prf.Sum(u)
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+// WhirlpoolKey derives a key with PBKDF2-HMAC-Whirlpool, as used by
+// TrueCrypt and VeraCrypt volume headers.
+func WhirlpoolKey(password, salt []byte, iter, keyLen int) []byte {
+	return Key(whirlpool.New, password, salt, iter, keyLen)
+}
+
+// RIPEMD160Key derives a key with PBKDF2-HMAC-RIPEMD-160, as used by
+// TrueCrypt and VeraCrypt volume headers.
+func RIPEMD160Key(password, salt []byte, iter, keyLen int) []byte {
+	return Key(ripemd160.New, password, salt, iter, keyLen)
+}
+
+// RIPEMD320Key derives a key with PBKDF2-HMAC-RIPEMD-320.
+func RIPEMD320Key(password, salt []byte, iter, keyLen int) []byte {
+	return Key(ripemd320.New, password, salt, iter, keyLen)
+}
+
+// A PRF names the hash underlying the HMAC of an encoded verifier.
+type PRF string
+
+const (
+	Whirlpool PRF = "whirlpool"
+	RIPEMD160 PRF = "ripemd160"
+	RIPEMD320 PRF = "ripemd320"
+)
+
+// Hash returns the constructor of the hash named by p.
+func (p PRF) Hash() (func() hash.Hash, error) {
+	switch p {
+	case Whirlpool:
+		return whirlpool.New, nil
+	case RIPEMD160:
+		return ripemd160.New, nil
+	case RIPEMD320:
+		return ripemd320.New, nil
+	}
+	return nil, fmt.Errorf("kdf: unsupported PRF %q", string(p))
+}
+
+// Params are the parameters of an encoded password verifier.
+type Params struct {
+	PRF        PRF
+	Iterations int
+	KeyLen     int
+}
+
+// MaxIterations bounds the iteration count of encoded verifiers, so
+// that a forged verifier cannot make Verify run for hours.
+const MaxIterations = 10000000
+
+var (
+	ErrInvalidEncoding = errors.New("kdf: invalid encoded verifier")
+	ErrInvalidParams   = errors.New("kdf: key and salt lengths must be positive, iterations between 1 and MaxIterations")
+)
+
+var b64 = base64.RawStdEncoding
+
+// Encode derives a key from password and salt and returns it in the
+// PHC string format, e.g.
+//
+//	$pbkdf2-whirlpool$i=100000$c2FsdA$...
+func Encode(password, salt []byte, p Params) (string, error) {
+	h, err := p.PRF.Hash()
+	if err != nil {
+		return "", err
+	}
+	if p.Iterations <= 0 || p.Iterations > MaxIterations || p.KeyLen <= 0 || len(salt) == 0 {
+		return "", ErrInvalidParams
+	}
+	key := Key(h, password, salt, p.Iterations, p.KeyLen)
+	return "$pbkdf2-" + string(p.PRF) +
+		"$i=" + strconv.Itoa(p.Iterations) +
+		"$" + b64.EncodeToString(salt) +
+		"$" + b64.EncodeToString(key), nil
+}
+
+// Decode parses a verifier produced by Encode.
+func Decode(encoded string) (p Params, salt, key []byte, err error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 5 || fields[0] != "" || !strings.HasPrefix(fields[1], "pbkdf2-") ||
+		!strings.HasPrefix(fields[2], "i=") {
+		return p, nil, nil, ErrInvalidEncoding
+	}
+	p.PRF = PRF(strings.TrimPrefix(fields[1], "pbkdf2-"))
+	if _, err = p.PRF.Hash(); err != nil {
+		return p, nil, nil, err
+	}
+	if p.Iterations, err = strconv.Atoi(fields[2][2:]); err != nil {
+		return p, nil, nil, ErrInvalidEncoding
+	}
+	if salt, err = b64.DecodeString(fields[3]); err != nil {
+		return p, nil, nil, ErrInvalidEncoding
+	}
+	if key, err = b64.DecodeString(fields[4]); err != nil {
+		return p, nil, nil, ErrInvalidEncoding
+	}
+	p.KeyLen = len(key)
+	if p.Iterations <= 0 || p.Iterations > MaxIterations || p.KeyLen == 0 || len(salt) == 0 {
+		return p, nil, nil, ErrInvalidParams
+	}
+	return p, salt, key, nil
+}
+
+// Verify reports whether password matches the encoded verifier. The
+// derived keys are compared in constant time.
+func Verify(encoded string, password []byte) (bool, error) {
+	p, salt, key, err := Decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	h, _ := p.PRF.Hash()
+	derived := Key(h, password, salt, p.Iterations, p.KeyLen)
+	return subtle.ConstantTimeCompare(derived, key) == 1, nil
+}
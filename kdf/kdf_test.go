@@ -0,0 +1,144 @@
+package kdf
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"strings"
+	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/whirlpool"
+)
+
+func TestKeyRFC6070(t *testing.T) {
+	// PBKDF2-HMAC-SHA1 vectors validating the construction itself.
+	assertKey(t, sha1.New, "password", "salt", 1, 20, "0c60c80f961f0e71f3a9b524af6012062fe037a6")
+	assertKey(t, sha1.New, "password", "salt", 2, 20, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957")
+	assertKey(t, sha1.New, "password", "salt", 4096, 20, "4b007901b765489abead49d926f721d065a429c1")
+	assertKey(t, sha1.New, "passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 25, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038")
+	assertKey(t, sha1.New, "pass\x00word", "sa\x00lt", 4096, 16, "56fa6aa75548099dcc37d7f03425e0c3")
+}
+
+func TestWhirlpoolKey(t *testing.T) {
+	assertKeyBytes(t, WhirlpoolKey([]byte("password"), []byte("salt"), 1, 64),
+		"7e25009bf8afade8ab33911d331b5b3e987fc7c3e2d5fdb3f33c183e837c357850a75eb8baad2c05b1e3bc7068c2a2d5c0f3e586f401610ad02f525c8fcf2cbd")
+	assertKeyBytes(t, WhirlpoolKey([]byte("password"), []byte("salt"), 2, 64),
+		"110b2e4266f03c334f6085bf421a68d6976a2f767e0bb6041a9c9315ec0d249fc8cb5fac1f9f3b87dbb98e9b4b220dfe0d6b55f88109dd558c30f0a0356f7d9f")
+	assertKeyBytes(t, WhirlpoolKey([]byte("passwordPASSWORDpassword"), []byte("saltSALTsaltSALTsaltSALTsaltSALTsalt"), 50, 100),
+		"8fbb57afe6d8b48ce8d36750bee0ca2bdef579073ff0b93d030105090d15fb99d8d6c0adc48e165227f9b62e6e46be4f651b84dbea62fd3228f27615d967e0ad64778c138a3135fbc3ccfadfb434558d76526c6dec77aafb9defed2d5e5bcbcf29a901bf")
+}
+
+func TestRIPEMD160Key(t *testing.T) {
+	assertKeyBytes(t, RIPEMD160Key([]byte("password"), []byte("salt"), 1, 20),
+		"b725258b125e0bacb0e2307e34feb16a4d0d6aed")
+	assertKeyBytes(t, RIPEMD160Key([]byte("password"), []byte("salt"), 2, 32),
+		"768dcc27b7bfdef794a1ff9d935090fcf598555e66913180b9ce363c615e9ed9")
+}
+
+func TestRIPEMD320Key(t *testing.T) {
+	assertKeyBytes(t, RIPEMD320Key([]byte("password"), []byte("salt"), 1, 40),
+		"d9ca1425ff7bef770b653d4d1fc2b613392d1686d504aa842f970e44732d262fbad1d05c24b697ff")
+	assertKeyBytes(t, RIPEMD320Key([]byte("password"), []byte("salt"), 2, 40),
+		"3ed34b76f087b32852e7298b9ebc1efe284977cf972c3785c3f24c508b28464583fb4e4a2cd22188")
+	assertKeyBytes(t, RIPEMD320Key([]byte("passwordPASSWORDpassword"), []byte("saltSALTsaltSALTsaltSALTsaltSALTsalt"), 50, 100),
+		"e19f75d7c6ef5663c9a82bb18ef12ba4e70cb7650b39873b20ca01b4825d1307c530135ed6c3950e98615cf98bdcc70e1583467a272b45cd87733153e4c667564c9c95de22ecdc984a865d58c45824e348e51bd390490893508e73c3d5e5b8a833e4d5f4")
+}
+
+func TestEncodeVerify(t *testing.T) {
+	encoded, err := Encode([]byte("password"), []byte("salt"), Params{PRF: Whirlpool, Iterations: 2, KeyLen: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "$pbkdf2-whirlpool$i=2$c2FsdA$EQsuQmbwPDNPYIW/Qhpo1g"
+	if encoded != expected {
+		t.Errorf("Expected %v, got %v", expected, encoded)
+	}
+
+	p, salt, key, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != (Params{PRF: Whirlpool, Iterations: 2, KeyLen: 16}) || string(salt) != "salt" {
+		t.Errorf("Unexpected decoded params %+v and salt %q", p, salt)
+	}
+	assertKeyBytes(t, key, "110b2e4266f03c334f6085bf421a68d6")
+
+	for password, expected := range map[string]bool{"password": true, "Password": false, "": false} {
+		if ok, err := Verify(encoded, []byte(password)); ok != expected || err != nil {
+			t.Errorf("Verify(%q): Expected %v, got %v, %v", password, expected, ok, err)
+		}
+	}
+
+	encoded, _ = Encode([]byte("password"), []byte("salt"), Params{PRF: RIPEMD320, Iterations: 1, KeyLen: 40})
+	if ok, err := Verify(encoded, []byte("password")); !ok || err != nil {
+		t.Errorf("Expected RIPEMD-320 verifier %v to verify, got %v", encoded, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		"$pbkdf2-whirlpool$i=2$c2FsdA",
+		"$pbkdf2-sha1$i=2$c2FsdA$EQsuQmbwPDNPYIW/QhpoQQ",
+		"$scrypt-whirlpool$i=2$c2FsdA$EQsuQmbwPDNPYIW/QhpoQQ",
+		"$pbkdf2-whirlpool$n=2$c2FsdA$EQsuQmbwPDNPYIW/QhpoQQ",
+		"$pbkdf2-whirlpool$i=0$c2FsdA$EQsuQmbwPDNPYIW/QhpoQQ",
+		"$pbkdf2-whirlpool$i=2$c2Fsd!$EQsuQmbwPDNPYIW/QhpoQQ",
+		"$pbkdf2-whirlpool$i=2$$EQsuQmbwPDNPYIW/QhpoQQ",
+	} {
+		if _, _, _, err := Decode(encoded); err == nil {
+			t.Errorf("Expected an error decoding %q", encoded)
+		}
+	}
+	for _, encoded := range []string{
+		"$pbkdf2-whirlpool$i=2000000000$c2FsdA$EQsuQmbwPDNPYIW/QhpoQQ",
+		"$pbkdf2-whirlpool$i=-1$c2FsdA$EQsuQmbwPDNPYIW/QhpoQQ",
+	} {
+		if _, _, _, err := Decode(encoded); err != ErrInvalidParams {
+			t.Errorf("%q: Expected ErrInvalidParams, got %v", encoded, err)
+		}
+		if ok, err := Verify(encoded, []byte("password")); ok || err != ErrInvalidParams {
+			t.Errorf("%q: Expected ErrInvalidParams, got %v, %v", encoded, ok, err)
+		}
+	}
+	if _, _, _, err := Decode("$pbkdf2-whirlpool$i=10000000$c2FsdA$EQsuQmbwPDNPYIW/QhpoQQ"); err != nil {
+		t.Errorf("Expected MaxIterations to decode, got %v", err)
+	}
+	if _, err := Encode([]byte("password"), nil, Params{PRF: Whirlpool, Iterations: 1, KeyLen: 16}); err != ErrInvalidParams {
+		t.Errorf("Expected ErrInvalidParams for an empty salt, got %v", err)
+	}
+	if _, err := Encode([]byte("password"), []byte("salt"), Params{PRF: Whirlpool, Iterations: MaxIterations + 1, KeyLen: 16}); err != ErrInvalidParams {
+		t.Errorf("Expected ErrInvalidParams for too many iterations, got %v", err)
+	}
+	if _, err := Encode([]byte("password"), []byte("salt"), Params{PRF: "md4", Iterations: 1, KeyLen: 16}); err == nil || !strings.Contains(err.Error(), "md4") {
+		t.Errorf("Expected an unsupported PRF error, got %v", err)
+	}
+}
+
+func TestKeyPreconditions(t *testing.T) {
+	// an iteration count below 1 counts as 1.
+	expected := hex.EncodeToString(Key(whirlpool.New, []byte("password"), []byte("salt"), 1, 16))
+	for _, iter := range []int{0, -5} {
+		assertKeyBytes(t, Key(whirlpool.New, []byte("password"), []byte("salt"), iter, 16), expected)
+	}
+	if key := Key(whirlpool.New, []byte("password"), []byte("salt"), 1, 0); len(key) != 0 {
+		t.Errorf("Expected an empty key, got %x", key)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a negative key length to panic")
+		}
+	}()
+	Key(whirlpool.New, []byte("password"), []byte("salt"), 1, -1)
+}
+
+func assertKey(t *testing.T, h func() hash.Hash, password, salt string, iter, keyLen int, expectedOutput string) {
+	assertKeyBytes(t, Key(h, []byte(password), []byte(salt), iter, keyLen), expectedOutput)
+}
+
+func assertKeyBytes(t *testing.T, key []byte, expectedOutput string) {
+	actual := hex.EncodeToString(key)
+	if expectedOutput != actual {
+		t.Errorf("Expected %v, got %v", expectedOutput, actual)
+	}
+}
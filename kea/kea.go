@@ -0,0 +1,196 @@
+// Package kea implements the Key Exchange Algorithm declassified with
+// SKIPJACK in 1998, a Diffie-Hellman key agreement in which each party
+// combines a static and an ephemeral key pair, and whose result is an
+// 80-bit SKIPJACK key:
+//
+//	SKIPJACK and KEA Algorithm Specifications, Version 2.0,
+//	NIST, 29 May 1998.
+//
+// Party A, with static key pair (xA, YA) and ephemeral key pair (rA, RA),
+// and party B likewise, both compute
+//
+//	w = YB^rA + RB^xA = YA^rB + RA^xB  mod p
+//
+// and derive the key from w with SKIPJACK.
+//
+// The derivation in DeriveKey follows the description of the
+// specification but has not been checked against its worked example,
+// which was not available when it was written. SharedSecret returns w
+// for systems that derive keys their own way.
+package kea
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/y3sh/go-legacy-crypto/skipjack"
+)
+
+// Sizes of the domain parameters, in bits.
+const (
+	PBits = 1024
+	QBits = 160
+)
+
+// KeySize is the size of the derived SKIPJACK key in bytes.
+const KeySize = skipjack.KeySize
+
+// Parameters are the domain parameters: primes P and Q with Q dividing
+// P-1, and a generator G of the subgroup of order Q. They have the form
+// of FIPS 186 DSA parameters of 1024 and 160 bits.
+type Parameters struct {
+	P, Q, G *big.Int
+}
+
+// PublicKey is a static or ephemeral public value.
+type PublicKey struct {
+	Parameters
+	Y *big.Int
+}
+
+// PrivateKey is a static or ephemeral key pair.
+type PrivateKey struct {
+	PublicKey
+	X *big.Int
+}
+
+// ErrInvalidPublicKey is returned for public values outside of the
+// subgroup of order Q.
+var ErrInvalidPublicKey = errors.New("kea: invalid public key")
+
+var one = big.NewInt(1)
+
+// Validate checks the sizes of the parameters and that G generates a
+// subgroup of order Q. It does not test P and Q for primality.
+func (params *Parameters) Validate() error {
+	if params.P == nil || params.Q == nil || params.G == nil {
+		return errors.New("kea: missing parameters")
+	}
+	if params.P.BitLen() != PBits || params.Q.BitLen() != QBits {
+		return fmt.Errorf("kea: parameters of %d and %d bits, expected %d and %d",
+			params.P.BitLen(), params.Q.BitLen(), PBits, QBits)
+	}
+	if new(big.Int).Mod(new(big.Int).Sub(params.P, one), params.Q).Sign() != 0 {
+		return errors.New("kea: Q does not divide P-1")
+	}
+	if params.G.Cmp(one) <= 0 || params.G.Cmp(params.P) >= 0 ||
+		new(big.Int).Exp(params.G, params.Q, params.P).Cmp(one) != 0 {
+		return errors.New("kea: G does not generate a subgroup of order Q")
+	}
+	return nil
+}
+
+// GenerateKey generates a key pair, static or ephemeral, with
+// randomness from rand.
+func GenerateKey(params *Parameters, rand io.Reader) (*PrivateKey, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	// X is uniform in [1, Q-1], from 64 extra bits reduced modulo Q-1.
+	b := make([]byte, QBits/8+8)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+	qm1 := new(big.Int).Sub(params.Q, one)
+	x := new(big.Int).SetBytes(b)
+	x.Mod(x, qm1).Add(x, one)
+	priv := &PrivateKey{X: x}
+	priv.Parameters = *params
+	priv.Y = new(big.Int).Exp(params.G, x, params.P)
+	return priv, nil
+}
+
+// Validate checks that pub is in the subgroup of order Q: 1 < Y < P
+// and Y^Q = 1 mod P.
+func (pub *PublicKey) Validate() error {
+	if pub.Y == nil || pub.Y.Cmp(one) <= 0 || pub.Y.Cmp(pub.P) >= 0 ||
+		new(big.Int).Exp(pub.Y, pub.Q, pub.P).Cmp(one) != 0 {
+		return ErrInvalidPublicKey
+	}
+	return nil
+}
+
+// sameParameters reports whether b, which may be incomplete, equals the
+// validated parameters a.
+func sameParameters(a, b *Parameters) bool {
+	if b.P == nil || b.Q == nil || b.G == nil {
+		return false
+	}
+	return a.P.Cmp(b.P) == 0 && a.Q.Cmp(b.Q) == 0 && a.G.Cmp(b.G) == 0
+}
+
+// SharedSecret returns w = peerStatic^ephemeral + peerEphemeral^static
+// mod P, after validating the parameters and both public values of the
+// peer. Both parties obtain the same w.
+func SharedSecret(static, ephemeral *PrivateKey, peerStatic, peerEphemeral *PublicKey) (*big.Int, error) {
+	params := &static.Parameters
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	for _, other := range []*Parameters{&ephemeral.Parameters, &peerStatic.Parameters, &peerEphemeral.Parameters} {
+		if !sameParameters(params, other) {
+			return nil, errors.New("kea: keys with different parameters")
+		}
+	}
+	if static.X == nil || ephemeral.X == nil {
+		return nil, errors.New("kea: missing private key")
+	}
+	if err := peerStatic.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: static", err)
+	}
+	if err := peerEphemeral.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: ephemeral", err)
+	}
+	t := new(big.Int).Exp(peerEphemeral.Y, static.X, params.P)
+	u := new(big.Int).Exp(peerStatic.Y, ephemeral.X, params.P)
+	w := t.Add(t, u)
+	return w.Mod(w, params.P), nil
+}
+
+// pad is XORed with the first 80 bits of w to form the SKIPJACK key of
+// the derivation.
+var pad = [KeySize]byte{0x72, 0xf1, 0xa8, 0x7e, 0x92, 0x82, 0x41, 0x98, 0xab, 0x0b}
+
+// DeriveKey derives the 80-bit SKIPJACK key from w, written as 128
+// bytes. With v1 the first 80 bits of w and v2 the next 80 bits, v2 is
+// encrypted with SKIPJACK under the key v1 XOR pad, chaining its 64-bit
+// and 16-bit parts as in CBC mode:
+//
+//	E1 = SKIPJACK(v2[0:64])
+//	E2 = SKIPJACK(E1 XOR v2[64:80] || 0^48)
+//	K  = E1 || E2[0:16]
+func DeriveKey(w *big.Int) ([]byte, error) {
+	if w.Sign() < 0 || w.BitLen() > PBits {
+		return nil, errors.New("kea: shared secret out of range")
+	}
+	b := w.FillBytes(make([]byte, PBits/8))
+	var t [KeySize]byte
+	subtle.XORBytes(t[:], b[:KeySize], pad[:])
+	c, err := skipjack.NewCipher(t[:])
+	if err != nil {
+		return nil, err
+	}
+	v2 := b[KeySize : 2*KeySize]
+	key := make([]byte, skipjack.BlockSize+2)
+	c.Encrypt(key, v2[:skipjack.BlockSize])
+	var e2 [skipjack.BlockSize]byte
+	copy(e2[:], key)
+	e2[0] ^= v2[8]
+	e2[1] ^= v2[9]
+	c.Encrypt(e2[:], e2[:])
+	copy(key[skipjack.BlockSize:], e2[:2])
+	return key, nil
+}
+
+// Agree runs the key agreement and returns the SKIPJACK key, that is
+// DeriveKey of SharedSecret.
+func Agree(static, ephemeral *PrivateKey, peerStatic, peerEphemeral *PublicKey) ([]byte, error) {
+	w, err := SharedSecret(static, ephemeral, peerStatic, peerEphemeral)
+	if err != nil {
+		return nil, err
+	}
+	return DeriveKey(w)
+}
@@ -0,0 +1,174 @@
+package kea
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// The worked example of the specification was not available; the
+// parameters below were generated with OpenSSL and the expected values
+// computed with an independent implementation of the algorithm as
+// described in DeriveKey.
+var testParams = Parameters{
+	P: fromHex("" +
+		"e74eb4dd829beb45f2b116a4838926a5d357bd505e314f57326275015f48b904" +
+		"fb6dfa5942b982efc24c6cc313f3c6e0f0bf37f511df0357f07bb07bff13fd57" +
+		"9ff239f4fb9251a7e9c5062543bd8a52c240f85ca3a8c34daf5fd675664230cb" +
+		"dc7bbfa9a938ea430873a14fa119c3712e704c7ffb0301bb1c6fab3f84b5451d"),
+	Q: fromHex("b0463101dc5ae4b5ffc512b5090cab0091eb4301"),
+	G: fromHex("" +
+		"abd083fb9893a50eda5ccc2cf3c341a96ddf24a7fe8151338be6c364073262d9" +
+		"8274b785559c7d514e5919324a6808a85e6bf347c29be12449b433d6ae1bfd1f" +
+		"d8071a8d1d088d32649851145cfc8a06e208eb7474fc60777d6e02a622e4ff16" +
+		"3a051d351a0a9f63c9694e6bb675ac3ffe311a5f374408d548b495f605bfb5b8"),
+}
+
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bad hex " + s)
+	}
+	return n
+}
+
+func newKey(x string) *PrivateKey {
+	priv := &PrivateKey{X: fromHex(x)}
+	priv.Parameters = testParams
+	priv.Y = new(big.Int).Exp(testParams.G, priv.X, testParams.P)
+	return priv
+}
+
+func TestAgree(t *testing.T) {
+	staticA := newKey("1234567890abcdef1234567890abcdef12345678")
+	ephemeralA := newKey("0fedcba9876543210fedcba9876543210fedcba9")
+	staticB := newKey("2468ace02468ace02468ace02468ace02468ace0")
+	ephemeralB := newKey("13579bdf13579bdf13579bdf13579bdf13579bdf")
+
+	w, err := SharedSecret(staticA, ephemeralA, &staticB.PublicKey, &ephemeralB.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedW := "" +
+		"858f65bf017fc86de8ad5dc8eba0d30b4be025f5f6c6c045c7c2a2808e857850" +
+		"5588a3994bc383452b566d96a724b688a2a4d85b264197ef34383996436f9da5" +
+		"5751ec3374ef47614edee3b4fd76e4ec93ef1f4104dbe0bec6c11abc713e0336" +
+		"66493cac8f1814da5063ad3e6ae7ad9262c1ca566649c850ef7abab17c4db736"
+	if actual := hex.EncodeToString(w.FillBytes(make([]byte, PBits/8))); actual != expectedW {
+		t.Errorf("Expected %v, got %v", expectedW, actual)
+	}
+
+	keyA, err := Agree(staticA, ephemeralA, &staticB.PublicKey, &ephemeralB.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := Agree(staticB, ephemeralB, &staticA.PublicKey, &ephemeralA.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "9b516bc9869b9da01731"
+	if actual := hex.EncodeToString(keyA); actual != expected {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	if actual := hex.EncodeToString(keyB); actual != expected {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	var keys [4]*PrivateKey
+	for i := range keys {
+		var err error
+		if keys[i], err = GenerateKey(&testParams, rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+		if keys[i].X.Sign() <= 0 || keys[i].X.Cmp(testParams.Q) >= 0 {
+			t.Fatalf("Expected 0 < X < Q, got %v", keys[i].X)
+		}
+		if err := keys[i].PublicKey.Validate(); err != nil {
+			t.Fatalf("Expected a valid public key, got %v", err)
+		}
+	}
+	keyA, err := Agree(keys[0], keys[1], &keys[2].PublicKey, &keys[3].PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := Agree(keys[2], keys[3], &keys[0].PublicKey, &keys[1].PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keyA) != KeySize || hex.EncodeToString(keyA) != hex.EncodeToString(keyB) {
+		t.Errorf("Expected equal keys of %d bytes, got %x and %x", KeySize, keyA, keyB)
+	}
+}
+
+func TestInvalidPublicKeys(t *testing.T) {
+	static, ephemeral := newKey("1234"), newKey("5678")
+	pm1 := new(big.Int).Sub(testParams.P, one)
+	for _, y := range []*big.Int{nil, big.NewInt(0), big.NewInt(1), pm1, testParams.P, big.NewInt(2)} {
+		bad := &PublicKey{Parameters: testParams, Y: y}
+		if _, err := Agree(static, ephemeral, bad, &ephemeral.PublicKey); !errors.Is(err, ErrInvalidPublicKey) {
+			t.Errorf("Static %v: Expected %v, got %v", y, ErrInvalidPublicKey, err)
+		}
+		if _, err := Agree(static, ephemeral, &static.PublicKey, bad); !errors.Is(err, ErrInvalidPublicKey) {
+			t.Errorf("Ephemeral %v: Expected %v, got %v", y, ErrInvalidPublicKey, err)
+		}
+	}
+
+	other := newKey("9abc")
+	other.P = new(big.Int).Add(testParams.P, big.NewInt(2))
+	if _, err := Agree(static, ephemeral, &other.PublicKey, &ephemeral.PublicKey); err == nil {
+		t.Errorf("Expected an error for keys with different parameters")
+	}
+
+	// incomplete keys are rejected, not dereferenced.
+	for _, params := range []Parameters{{}, {P: testParams.P}, {P: testParams.P, Q: testParams.Q}} {
+		incomplete := &PublicKey{Parameters: params, Y: static.Y}
+		if _, err := Agree(static, ephemeral, incomplete, &ephemeral.PublicKey); err == nil {
+			t.Errorf("Expected an error for a peer static key with parameters %v", params)
+		}
+		if _, err := Agree(static, ephemeral, &static.PublicKey, incomplete); err == nil {
+			t.Errorf("Expected an error for a peer ephemeral key with parameters %v", params)
+		}
+		private := &PrivateKey{PublicKey: *incomplete, X: ephemeral.X}
+		if _, err := Agree(static, private, &static.PublicKey, &ephemeral.PublicKey); err == nil {
+			t.Errorf("Expected an error for an ephemeral key with parameters %v", params)
+		}
+	}
+	noX := &PrivateKey{PublicKey: ephemeral.PublicKey}
+	if _, err := Agree(static, noX, &static.PublicKey, &ephemeral.PublicKey); err == nil {
+		t.Errorf("Expected an error for a private key without X")
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	if err := testParams.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	bad := []Parameters{
+		{},
+		{P: testParams.P, Q: testParams.P, G: testParams.G},
+		{P: testParams.P, Q: new(big.Int).Add(testParams.Q, big.NewInt(2)), G: testParams.G},
+		{P: testParams.P, Q: testParams.Q, G: big.NewInt(1)},
+		{P: testParams.P, Q: testParams.Q, G: big.NewInt(2)},
+	}
+	for i, params := range bad {
+		if err := params.Validate(); err == nil {
+			t.Errorf("%d: Expected an error", i)
+		}
+		if _, err := GenerateKey(&params, rand.Reader); err == nil {
+			t.Errorf("%d: Expected an error from GenerateKey", i)
+		}
+	}
+}
+
+func TestDeriveKeyRange(t *testing.T) {
+	if _, err := DeriveKey(big.NewInt(-1)); err == nil {
+		t.Errorf("Expected an error for a negative secret")
+	}
+	if _, err := DeriveKey(new(big.Int).Lsh(one, PBits)); err == nil {
+		t.Errorf("Expected an error for a secret of more than %d bits", PBits)
+	}
+}
@@ -0,0 +1,247 @@
+// Package legacy selects the hash functions of this module by name,
+// alias or ASN.1 object identifier, for programs that choose their
+// algorithms from configuration.
+//
+// Names are matched ignoring case, dashes, underscores and spaces, so
+// "RIPEMD-320", "ripemd320" and "rmd_320" are the same name. A name
+// made of dotted decimal numbers is taken as an object identifier.
+package legacy
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/y3sh/go-legacy-crypto/md2"
+	"github.com/y3sh/go-legacy-crypto/ripemd160"
+	"github.com/y3sh/go-legacy-crypto/ripemd320"
+	"github.com/y3sh/go-legacy-crypto/whirlpool"
+)
+
+// A Hash describes a hash function.
+type Hash struct {
+	Name      string   // canonical name
+	Aliases   []string // other names
+	OIDs      []asn1.ObjectIdentifier
+	Size      int // size of the checksum in bytes
+	BlockSize int
+	New       func() hash.Hash
+}
+
+// ErrUnknown is returned, wrapped, for names and object identifiers of
+// no registered hash.
+var ErrUnknown = errors.New("legacy: unknown hash")
+
+// Object identifiers of the registered hashes.
+var (
+	// ISO/IEC 10118-3 dedicated hash functions
+	OIDRIPEMD160ISO = asn1.ObjectIdentifier{1, 0, 10118, 3, 0, 49}
+	OIDWhirlpool    = asn1.ObjectIdentifier{1, 0, 10118, 3, 0, 55}
+	// TeleTrusT algorithm arc
+	OIDRIPEMD160 = asn1.ObjectIdentifier{1, 3, 36, 3, 2, 1}
+	// RSADSI digest algorithm arc
+	OIDMD2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 2}
+)
+
+var (
+	mu     sync.RWMutex
+	byName = map[string]*Hash{}
+	byOID  = map[string]*Hash{}
+	hashes []*Hash
+)
+
+func init() {
+	for _, h := range []*Hash{
+		{
+			Name:      "MD2",
+			OIDs:      []asn1.ObjectIdentifier{OIDMD2},
+			Size:      md2.Size,
+			BlockSize: md2.BlockSize,
+			New:       md2.New,
+		},
+		{
+			Name:      "RIPEMD-160",
+			Aliases:   []string{"rmd160"},
+			OIDs:      []asn1.ObjectIdentifier{OIDRIPEMD160, OIDRIPEMD160ISO},
+			Size:      ripemd160.Size,
+			BlockSize: ripemd160.BlockSize,
+			New:       ripemd160.New,
+		},
+		{
+			Name:      "RIPEMD-320",
+			Aliases:   []string{"rmd320"},
+			Size:      ripemd320.Size,
+			BlockSize: ripemd320.BlockSize,
+			New:       ripemd320.New,
+		},
+		{
+			Name:      "Whirlpool",
+			Aliases:   []string{"wp512", "whirlpool512"},
+			OIDs:      []asn1.ObjectIdentifier{OIDWhirlpool},
+			Size:      whirlpool.Size,
+			BlockSize: whirlpool.BlockSize,
+			New:       whirlpool.New,
+		},
+		{
+			Name:      "wp384",
+			Aliases:   []string{"whirlpool384"},
+			Size:      whirlpool.Size384,
+			BlockSize: whirlpool.BlockSize,
+			New:       whirlpool.New384,
+		},
+		{
+			Name:      "wp256",
+			Aliases:   []string{"whirlpool256"},
+			Size:      whirlpool.Size256,
+			BlockSize: whirlpool.BlockSize,
+			New:       whirlpool.New256,
+		},
+		{
+			Name:      "Whirlpool-T",
+			Size:      whirlpool.Size,
+			BlockSize: whirlpool.BlockSize,
+			New:       func() hash.Hash { return whirlpool.NewVariant(whirlpool.WhirlpoolT) },
+		},
+		{
+			Name:      "Whirlpool-0",
+			Size:      whirlpool.Size,
+			BlockSize: whirlpool.BlockSize,
+			New:       func() hash.Hash { return whirlpool.NewVariant(whirlpool.Whirlpool0) },
+		},
+	} {
+		if err := Register(h); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// normalize folds the spellings of a name into one key.
+func normalize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// Register adds h to the registry, for hashes defined outside of this
+// module. It fails if a name, alias or object identifier of h is
+// already taken.
+func Register(h *Hash) error {
+	if h.Name == "" || h.New == nil || h.Size <= 0 || h.BlockSize <= 0 {
+		return fmt.Errorf("legacy: incomplete description of %q", h.Name)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	names := append([]string{h.Name}, h.Aliases...)
+	for _, name := range names {
+		if _, ok := byName[normalize(name)]; ok {
+			return fmt.Errorf("legacy: name %q already registered", name)
+		}
+	}
+	for _, oid := range h.OIDs {
+		if _, ok := byOID[oid.String()]; ok {
+			return fmt.Errorf("legacy: object identifier %v already registered", oid)
+		}
+	}
+	for _, name := range names {
+		byName[normalize(name)] = h
+	}
+	for _, oid := range h.OIDs {
+		byOID[oid.String()] = h
+	}
+	hashes = append(hashes, h)
+	return nil
+}
+
+// Unregister removes h, added with Register, from the registry. It does
+// nothing if h is not registered.
+func Unregister(h *Hash) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range append([]string{h.Name}, h.Aliases...) {
+		if byName[normalize(name)] == h {
+			delete(byName, normalize(name))
+		}
+	}
+	for _, oid := range h.OIDs {
+		if byOID[oid.String()] == h {
+			delete(byOID, oid.String())
+		}
+	}
+	for i, registered := range hashes {
+		if registered == h {
+			hashes = append(hashes[:i:i], hashes[i+1:]...)
+			break
+		}
+	}
+}
+
+// Lookup returns the hash with the given name, alias or dotted
+// object identifier.
+func Lookup(name string) (*Hash, error) {
+	if oid, ok := parseOID(name); ok {
+		return LookupOID(oid)
+	}
+	mu.RLock()
+	h, ok := byName[normalize(name)]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+	return h, nil
+}
+
+// LookupOID returns the hash with the given object identifier.
+func LookupOID(oid asn1.ObjectIdentifier) (*Hash, error) {
+	mu.RLock()
+	h, ok := byOID[oid.String()]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %v", ErrUnknown, oid)
+	}
+	return h, nil
+}
+
+// New returns a new hash.Hash computing the hash with the given name,
+// alias or dotted object identifier.
+func New(name string) (hash.Hash, error) {
+	h, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return h.New(), nil
+}
+
+// Hashes returns every registered hash, sorted by name.
+func Hashes() []*Hash {
+	mu.RLock()
+	list := append([]*Hash(nil), hashes...)
+	mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// parseOID parses a dotted decimal object identifier.
+func parseOID(s string) (asn1.ObjectIdentifier, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, false
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		oid[i] = n
+	}
+	return oid, true
+}
@@ -0,0 +1,145 @@
+package legacy
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/ripemd160"
+	"github.com/y3sh/go-legacy-crypto/whirlpool"
+)
+
+func TestLookup(t *testing.T) {
+	vectors := []struct {
+		name, canonical string
+		size, blockSize int
+	}{
+		{"RIPEMD-160", "RIPEMD-160", 20, 64},
+		{"rmd160", "RIPEMD-160", 20, 64},
+		{"1.3.36.3.2.1", "RIPEMD-160", 20, 64},
+		{"1.0.10118.3.0.49", "RIPEMD-160", 20, 64},
+		{"ripemd320", "RIPEMD-320", 40, 64},
+		{"rmd320", "RIPEMD-320", 40, 64},
+		{"RMD_320", "RIPEMD-320", 40, 64},
+		{"WHIRLPOOL", "Whirlpool", 64, 64},
+		{"1.0.10118.3.0.55", "Whirlpool", 64, 64},
+		{"wp384", "wp384", 48, 64},
+		{"Whirlpool 256", "wp256", 32, 64},
+		{"whirlpool-t", "Whirlpool-T", 64, 64},
+		{"Whirlpool0", "Whirlpool-0", 64, 64},
+		{"md2", "MD2", 16, 16},
+		{"1.2.840.113549.2.2", "MD2", 16, 16},
+	}
+	for _, v := range vectors {
+		h, err := Lookup(v.name)
+		if err != nil {
+			t.Errorf("%s: Expected no error, got %v", v.name, err)
+			continue
+		}
+		if h.Name != v.canonical || h.Size != v.size || h.BlockSize != v.blockSize {
+			t.Errorf("%s: Expected %s of size %d, got %s of size %d", v.name, v.canonical, v.size, h.Name, h.Size)
+		}
+		d := h.New()
+		if d.Size() != h.Size || d.BlockSize() != h.BlockSize {
+			t.Errorf("%s: Expected sizes %d and %d, got %d and %d", v.name, h.Size, h.BlockSize, d.Size(), d.BlockSize())
+		}
+	}
+}
+
+func TestLookupComputes(t *testing.T) {
+	d, err := New("rmd160")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Write([]byte("abc"))
+	expected := ripemd160.New()
+	expected.Write([]byte("abc"))
+	if hex.EncodeToString(d.Sum(nil)) != hex.EncodeToString(expected.Sum(nil)) {
+		t.Errorf("Expected %x, got %x", expected.Sum(nil), d.Sum(nil))
+	}
+	h, _ := LookupOID(OIDWhirlpool)
+	sum := whirlpool.Sum512([]byte("abc"))
+	d = h.New()
+	d.Write([]byte("abc"))
+	if hex.EncodeToString(d.Sum(nil)) != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected %x, got %x", sum, d.Sum(nil))
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	for _, name := range []string{"", "md4", "whirl", "1.2.3", "1.0.10118.3.0.50"} {
+		if _, err := Lookup(name); !errors.Is(err, ErrUnknown) {
+			t.Errorf("%q: Expected ErrUnknown, got %v", name, err)
+		}
+	}
+	if _, err := LookupOID(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Expected ErrUnknown, got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	newHash := func() hash.Hash { return ripemd160.New() }
+	custom := &Hash{Name: "Custom-160", OIDs: []asn1.ObjectIdentifier{{1, 2, 3, 4}}, Size: 20, BlockSize: 64, New: newHash}
+	if err := Register(custom); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Unregister(custom) })
+	if h, err := Lookup("custom160"); err != nil || h != custom {
+		t.Errorf("Expected the custom hash, got %v, %v", h, err)
+	}
+	if h, err := Lookup("1.2.3.4"); err != nil || h != custom {
+		t.Errorf("Expected the custom hash by OID, got %v, %v", h, err)
+	}
+	for _, h := range []*Hash{
+		{Name: "rmd-160", Size: 20, BlockSize: 64, New: newHash},
+		{Name: "Other", OIDs: []asn1.ObjectIdentifier{OIDWhirlpool}, Size: 20, BlockSize: 64, New: newHash},
+		{Name: "Incomplete"},
+	} {
+		if err := Register(h); err == nil {
+			t.Errorf("Expected %s not to register", h.Name)
+		}
+	}
+	if _, err := Lookup("Other"); err == nil {
+		t.Errorf("Expected a failed registration to leave no trace")
+	}
+	names := map[string]bool{}
+	for _, h := range Hashes() {
+		names[h.Name] = true
+	}
+	for _, name := range []string{"RIPEMD-160", "RIPEMD-320", "Whirlpool", "Custom-160"} {
+		if !names[name] {
+			t.Errorf("Expected %s among the hashes", name)
+		}
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	custom := &Hash{Name: "Scoped", Aliases: []string{"scoped-alias"}, OIDs: []asn1.ObjectIdentifier{{1, 2, 3, 5}},
+		Size: 20, BlockSize: 64, New: func() hash.Hash { return ripemd160.New() }}
+	if err := Register(custom); err != nil {
+		t.Fatal(err)
+	}
+	Unregister(custom)
+	for _, name := range []string{"Scoped", "scoped-alias", "1.2.3.5"} {
+		if _, err := Lookup(name); !errors.Is(err, ErrUnknown) {
+			t.Errorf("%q: Expected ErrUnknown, got %v", name, err)
+		}
+	}
+	for _, h := range Hashes() {
+		if h == custom {
+			t.Errorf("Expected Scoped not among the hashes")
+		}
+	}
+
+	// only the registered description is removed.
+	Unregister(&Hash{Name: "RIPEMD-160", OIDs: []asn1.ObjectIdentifier{OIDRIPEMD160}})
+	if _, err := Lookup("RIPEMD-160"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := Register(custom); err != nil {
+		t.Errorf("Expected to register again, got %v", err)
+	}
+	Unregister(custom)
+}
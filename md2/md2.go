@@ -0,0 +1,124 @@
+package md2
+
+// MD2 is the 128-bit message digest of RFC 1319, designed by Ron Rivest
+// for 8-bit machines. It is broken and only found in old certificates
+// signed with md2WithRSAEncryption.
+//
+// Specification: https://www.rfc-editor.org/rfc/rfc1319.
+import (
+	"hash"
+)
+
+// The size of the checksum in bytes.
+const Size = 16
+
+// The block size of the hash algorithm in bytes.
+const BlockSize = 16
+
+// piSubst is a permutation of 0..255 built from the digits of pi.
+var piSubst = [256]byte{
+	41, 46, 67, 201, 162, 216, 124, 1, 61, 54, 84, 161, 236, 240, 6, 19,
+	98, 167, 5, 243, 192, 199, 115, 140, 152, 147, 43, 217, 188, 76, 130, 202,
+	30, 155, 87, 60, 253, 212, 224, 22, 103, 66, 111, 24, 138, 23, 229, 18,
+	190, 78, 196, 214, 218, 158, 222, 73, 160, 251, 245, 142, 187, 47, 238, 122,
+	169, 104, 121, 145, 21, 178, 7, 63, 148, 194, 16, 137, 11, 34, 95, 33,
+	128, 127, 93, 154, 90, 144, 50, 39, 53, 62, 204, 231, 191, 247, 151, 3,
+	255, 25, 48, 179, 72, 165, 181, 209, 215, 94, 146, 42, 172, 86, 170, 198,
+	79, 184, 56, 210, 150, 164, 125, 182, 118, 252, 107, 226, 156, 116, 4, 241,
+	69, 157, 112, 89, 100, 113, 135, 32, 134, 91, 207, 101, 230, 45, 168, 2,
+	27, 96, 37, 173, 174, 176, 185, 246, 28, 70, 97, 105, 52, 64, 126, 15,
+	85, 71, 163, 35, 221, 81, 175, 58, 195, 92, 249, 206, 186, 197, 234, 38,
+	44, 83, 13, 110, 133, 40, 132, 9, 211, 223, 205, 244, 65, 129, 77, 82,
+	106, 220, 55, 200, 108, 193, 171, 250, 36, 225, 123, 8, 12, 189, 177, 74,
+	120, 136, 149, 139, 227, 99, 232, 109, 233, 203, 213, 254, 59, 0, 29, 57,
+	242, 239, 183, 14, 102, 88, 208, 228, 166, 119, 114, 248, 235, 117, 75, 10,
+	49, 68, 80, 180, 143, 237, 31, 26, 219, 153, 141, 51, 159, 17, 131, 20,
+}
+
+// digest represents the partial evaluation of a checksum.
+type digest struct {
+	s  [3 * BlockSize]byte // state; the first BlockSize bytes are the hash
+	c  [BlockSize]byte     // checksum
+	x  [BlockSize]byte     // temporary buffer
+	nx int                 // index into x
+}
+
+func (d *digest) Reset() {
+	*d = digest{}
+}
+
+// New returns a new hash.Hash computing the checksum.
+func New() hash.Hash {
+	selfTest.MustCheck()
+	return new(digest)
+}
+
+func (d *digest) Size() int { return Size }
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Write(p []byte) (nn int, err error) {
+	nn = len(p)
+	if d.nx > 0 {
+		n := copy(d.x[d.nx:], p)
+		d.nx += n
+		if d.nx == BlockSize {
+			d.block(d.x[:])
+			d.nx = 0
+		}
+		p = p[n:]
+	}
+	for len(p) >= BlockSize {
+		d.block(p[:BlockSize])
+		p = p[BlockSize:]
+	}
+	if len(p) > 0 {
+		d.nx = copy(d.x[:], p)
+	}
+	return
+}
+
+// block processes one block, updating the state and the checksum.
+func (d *digest) block(p []byte) {
+	d.compress(p)
+	l := d.c[BlockSize-1]
+	for j, b := range p[:BlockSize] {
+		d.c[j] ^= piSubst[b^l]
+		l = d.c[j]
+	}
+}
+
+// compress mixes one block into the state.
+func (d *digest) compress(p []byte) {
+	for j, b := range p[:BlockSize] {
+		d.s[BlockSize+j] = b
+		d.s[2*BlockSize+j] = b ^ d.s[j]
+	}
+	var t byte
+	for j := 0; j < 18; j++ {
+		for k := range d.s {
+			d.s[k] ^= piSubst[t]
+			t = d.s[k]
+		}
+		t += byte(j)
+	}
+}
+
+func (d0 *digest) Sum(in []byte) []byte {
+	// Make a copy of d0 so that caller can keep writing and summing.
+	d := *d0
+
+	// Padding.  Append i bytes of value i, 1 <= i <= BlockSize.
+	var tmp [BlockSize]byte
+	pad := BlockSize - d.nx
+	for i := 0; i < pad; i++ {
+		tmp[i] = byte(pad)
+	}
+	d.Write(tmp[:pad])
+
+	// The checksum goes through the compression function only.
+	c := d.c
+	d.compress(c[:])
+
+	return append(in, d.s[:Size]...)
+}
@@ -0,0 +1,27 @@
+package md2
+
+// Known-answer test run before first use, see package selftest.
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+var selfTest = selftest.Register("MD2", func() error {
+	vectors := []struct {
+		input, digest string
+	}{
+		{"abc", "da853b0d3f88d99b30283a69e6ded6bb"},
+		{"abcdefghijklmnopqrstuvwxyz", "4e8ddff3650292ab5a4108c3aa47940b"},
+	}
+	for _, v := range vectors {
+		// not through New, which waits for this test.
+		d := new(digest)
+		d.Write([]byte(v.input))
+		if hex.EncodeToString(d.Sum(nil)) != v.digest {
+			return errors.New("wrong digest")
+		}
+	}
+	return nil
+})
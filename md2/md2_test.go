@@ -0,0 +1,61 @@
+package md2
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/hashtest"
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+func TestMD2(t *testing.T) {
+	// RFC 1319, appendix A.5
+	assertHash(t, "", "8350e5a3e24c153df2275c9f80692773")
+	assertHash(t, "a", "32ec01ec4a6dac72c0ab96fb34c0b5d1")
+	assertHash(t, "abc", "da853b0d3f88d99b30283a69e6ded6bb")
+	assertHash(t, "message digest", "ab4f496bfb2a530b219ff33031fe06b0")
+	assertHash(t, "abcdefghijklmnopqrstuvwxyz", "4e8ddff3650292ab5a4108c3aa47940b")
+	assertHash(t, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789", "da33def2a42df13975352846c30338cd")
+	assertHash(t, strings.Repeat("1234567890", 8), "d5976f79d83d3a0dc9806c3c66f3efd8")
+}
+
+func assertHash(t *testing.T, input, expectedOutput string) {
+	h := New()
+	h.Write([]byte(input))
+	actual := hex.EncodeToString(h.Sum(nil))
+	if expectedOutput != actual {
+		t.Errorf("Expected %v, got %v", expectedOutput, actual)
+	}
+}
+
+func TestPiSubst(t *testing.T) {
+	var seen [256]bool
+	for _, v := range piSubst {
+		if seen[v] {
+			t.Fatalf("Expected a permutation, got %d twice", v)
+		}
+		seen[v] = true
+	}
+}
+
+func TestConformance(t *testing.T) {
+	hashtest.TestHash(t, New)
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := selftest.RunAll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	saved := selfTest
+	defer func() { selfTest = saved }()
+	selfTest = selftest.New("broken MD2", func() error { return errors.New("broken") })
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected New to panic")
+		}
+	}()
+	New()
+}
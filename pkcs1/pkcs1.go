@@ -0,0 +1,163 @@
+// Package pkcs1 signs and verifies RSASSA-PKCS1-v1_5 signatures, as
+// specified in RFC 8017, over the hashes of this module.
+//
+// crypto/rsa only knows the DigestInfo prefixes of the crypto.Hash
+// values, so this package encodes the DigestInfo itself, from the
+// object identifier of the hash in package legacy, and has crypto/rsa
+// sign the encoding as is.
+package pkcs1
+
+import (
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/y3sh/go-legacy-crypto/legacy"
+)
+
+// ErrNoOID is returned for hashes without an object identifier, such as
+// RIPEMD-320. Use SignOID and VerifyOID with an agreed upon identifier
+// instead.
+var ErrNoOID = errors.New("pkcs1: hash has no object identifier")
+
+// ErrVerification is returned for signatures that do not verify.
+var ErrVerification = errors.New("pkcs1: verification error")
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+// DigestInfo returns the DER encoding of the DigestInfo of digest,
+// computed with the hash identified by oid, with NULL parameters.
+func DigestInfo(oid asn1.ObjectIdentifier, digest []byte) ([]byte, error) {
+	return asn1.Marshal(digestInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+		Digest:    digest,
+	})
+}
+
+// digestInfoNoParams is DigestInfo with the parameters omitted, which
+// some signers do.
+func digestInfoNoParams(oid asn1.ObjectIdentifier, digest []byte) ([]byte, error) {
+	return asn1.Marshal(digestInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		Digest:    digest,
+	})
+}
+
+// prepare checks digest and returns the first object identifier of h.
+func prepare(h *legacy.Hash, digest []byte) (asn1.ObjectIdentifier, error) {
+	if len(h.OIDs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoOID, h.Name)
+	}
+	if err := checkDigest(h, digest); err != nil {
+		return nil, err
+	}
+	return h.OIDs[0], nil
+}
+
+func checkDigest(h *legacy.Hash, digest []byte) error {
+	if len(digest) != h.Size {
+		return fmt.Errorf("pkcs1: %s digest of %d bytes, expected %d", h.Name, len(digest), h.Size)
+	}
+	return nil
+}
+
+// Sign returns the RSASSA-PKCS1-v1_5 signature of digest, which must be
+// the result of hashing the message with h. random is passed on to
+// rsa.SignPKCS1v15.
+func Sign(random io.Reader, priv *rsa.PrivateKey, h *legacy.Hash, digest []byte) ([]byte, error) {
+	oid, err := prepare(h, digest)
+	if err != nil {
+		return nil, err
+	}
+	info, err := DigestInfo(oid, digest)
+	if err != nil {
+		return nil, err
+	}
+	return rsa.SignPKCS1v15(random, priv, 0, info)
+}
+
+// Verify checks the RSASSA-PKCS1-v1_5 signature sig of digest, the
+// result of hashing the message with h. Every object identifier of h
+// is accepted, with or without NULL parameters. A nil error means the
+// signature is valid.
+func Verify(pub *rsa.PublicKey, h *legacy.Hash, digest, sig []byte) error {
+	if _, err := prepare(h, digest); err != nil {
+		return err
+	}
+	for _, oid := range h.OIDs {
+		if err := verify(pub, oid, digest, sig); err != ErrVerification {
+			return err
+		}
+	}
+	return ErrVerification
+}
+
+// verify checks sig against the DigestInfo of digest under oid, with or
+// without NULL parameters.
+func verify(pub *rsa.PublicKey, oid asn1.ObjectIdentifier, digest, sig []byte) error {
+	for _, encode := range []func(asn1.ObjectIdentifier, []byte) ([]byte, error){DigestInfo, digestInfoNoParams} {
+		info, err := encode(oid, digest)
+		if err != nil {
+			return err
+		}
+		if rsa.VerifyPKCS1v15(pub, 0, info, sig) == nil {
+			return nil
+		}
+	}
+	return ErrVerification
+}
+
+// SignOID is like Sign, but identifies h in the DigestInfo by oid
+// rather than by the object identifiers of package legacy. It is meant
+// for hashes without an identifier, such as RIPEMD-320, under one agreed
+// upon with the verifier.
+func SignOID(random io.Reader, priv *rsa.PrivateKey, h *legacy.Hash, oid asn1.ObjectIdentifier, digest []byte) ([]byte, error) {
+	if err := checkDigest(h, digest); err != nil {
+		return nil, err
+	}
+	info, err := DigestInfo(oid, digest)
+	if err != nil {
+		return nil, err
+	}
+	return rsa.SignPKCS1v15(random, priv, 0, info)
+}
+
+// VerifyOID is like Verify, but accepts only oid as the identifier of h
+// in the DigestInfo, with or without NULL parameters.
+func VerifyOID(pub *rsa.PublicKey, h *legacy.Hash, oid asn1.ObjectIdentifier, digest, sig []byte) error {
+	if err := checkDigest(h, digest); err != nil {
+		return err
+	}
+	return verify(pub, oid, digest, sig)
+}
+
+// SignMessage hashes message with the hash of the given name, alias or
+// object identifier in package legacy, and signs the digest.
+func SignMessage(random io.Reader, priv *rsa.PrivateKey, hashName string, message []byte) ([]byte, error) {
+	h, err := legacy.Lookup(hashName)
+	if err != nil {
+		return nil, err
+	}
+	d := h.New()
+	d.Write(message)
+	return Sign(random, priv, h, d.Sum(nil))
+}
+
+// VerifyMessage hashes message with the hash of the given name, alias
+// or object identifier in package legacy, and verifies sig over the
+// digest.
+func VerifyMessage(pub *rsa.PublicKey, hashName string, message, sig []byte) error {
+	h, err := legacy.Lookup(hashName)
+	if err != nil {
+		return err
+	}
+	d := h.New()
+	d.Write(message)
+	return Verify(pub, h, d.Sum(nil), sig)
+}
@@ -0,0 +1,29 @@
+package ripemd160
+
+// Known-answer test run before first use, see package selftest.
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+var selfTest = selftest.Register("RIPEMD-160", func() error {
+	vectors := []struct {
+		input, digest string
+	}{
+		{"abc", "8eb208f7e05d987a9b044a8e98c6b087f15a0bfc"},
+		{strings.Repeat("1234567890", 8), "9b752e45573d4b39f4dbd3323cab82bf63326bfb"},
+	}
+	for _, v := range vectors {
+		// not through New, which waits for this test.
+		d := new(digest)
+		d.Reset()
+		d.Write([]byte(v.input))
+		if hex.EncodeToString(d.Sum(nil)) != v.digest {
+			return errors.New("wrong digest")
+		}
+	}
+	return nil
+})
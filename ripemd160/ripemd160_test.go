@@ -0,0 +1,130 @@
+package ripemd160
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
+	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/hashtest"
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+func TestRipemd160(t *testing.T) {
+	// https://homes.esat.kuleuven.be/~bosselae/ripemd160.html
+	assert160Hash(t, "", "9c1185a5c5e9fc54612808977ee8f548b2258d31")
+	assert160Hash(t, "a", "0bdc9d2d256b3ee9daae347be6f4dc835a467ffe")
+	assert160Hash(t, "abc", "8eb208f7e05d987a9b044a8e98c6b087f15a0bfc")
+	assert160Hash(t, "message digest", "5d0689ef49d2fae572b881b123a85ffa21595f36")
+	assert160Hash(t, "abcdefghijklmnopqrstuvwxyz", "f71c27109c692c1b56bbdceb5b9d2865b3708dbc")
+	assert160Hash(t, "abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq", "12a053384a9c0c88e405a06c27dcf49ada62eb2b")
+	assert160Hash(t, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789", "b0e20b6e3116640286ed3a87a5713079b21f5189")
+	assert160Hash(t, strings.Repeat("1234567890", 8), "9b752e45573d4b39f4dbd3323cab82bf63326bfb")
+	assert160Hash(t, strings.Repeat("a", 1000000), "52783243c1697bdbe16d37f97f68f08325dc1528")
+}
+
+func assert160Hash(t *testing.T, input, expectedOutput string) {
+	var h hash.Hash
+	h = New()
+	h.Write([]byte(input))
+	actual := hex.EncodeToString(h.Sum(nil))
+	if expectedOutput != actual {
+		t.Errorf("Expected %v, got %v", expectedOutput, actual)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	message := []byte(strings.Repeat("abcdefghijklmnopqrstuvwxyz", 10))
+	expected := New()
+	expected.Write(message)
+
+	for _, split := range []int{0, 1, 63, 64, 65, 100, len(message)} {
+		h := New()
+		h.Write(message[:split])
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write([]byte("garbage"))
+
+		resumed := New()
+		if err := resumed.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+			t.Fatal(err)
+		}
+		resumed.Write(message[split:])
+		if !bytes.Equal(resumed.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("Split at %d: Expected %x, got %x", split, expected.Sum(nil), resumed.Sum(nil))
+		}
+	}
+
+	state, _ := New().(encoding.BinaryMarshaler).MarshalBinary()
+	h := New().(encoding.BinaryUnmarshaler)
+	if err := h.UnmarshalBinary(state[:len(state)-1]); err == nil {
+		t.Errorf("Expected an error for a truncated state")
+	}
+	state[0] ^= 1
+	if err := h.UnmarshalBinary(state); err == nil {
+		t.Errorf("Expected an error for a state with an invalid identifier")
+	}
+}
+
+func TestNewFromDigest(t *testing.T) {
+	secret, message, suffix := []byte("secret key"), []byte("user=guest"), []byte(";admin=true")
+	mac := New()
+	mac.Write(secret)
+	mac.Write(message)
+
+	forger, glue, err := NewFromDigest(mac.Sum(nil), uint64(len(secret)+len(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forger.Write(suffix)
+
+	for _, n := range []int{0, 55, 56, 64, 119} {
+		if l := n + len(padding(uint64(n))); l%BlockSize != 0 {
+			t.Errorf("Expected padding of %d bytes to end on a block boundary, got %d", n, l)
+		}
+	}
+	expected := New()
+	expected.Write(secret)
+	expected.Write(message)
+	expected.Write(glue)
+	expected.Write(suffix)
+	if !bytes.Equal(forger.Sum(nil), expected.Sum(nil)) {
+		t.Errorf("Expected forged digest %x, got %x", expected.Sum(nil), forger.Sum(nil))
+	}
+	if _, _, err := NewFromDigest(make([]byte, Size-1), 0); err == nil {
+		t.Errorf("Expected an error for a short digest")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	hashtest.TestHash(t, New)
+}
+
+func TestNESSIE(t *testing.T) {
+	// the header of the file says where its vectors come from.
+	hashtest.TestNESSIE(t, New, "testdata/nessie.txt")
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := selftest.RunAll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	saved := selfTest
+	defer func() { selfTest = saved }()
+	selfTest = selftest.New("broken RIPEMD-160", func() error { return errors.New("broken") })
+	if _, _, err := NewFromDigest(make([]byte, Size), 0); err == nil {
+		t.Errorf("Expected NewFromDigest to fail")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected New to panic")
+		}
+	}()
+	New()
+}
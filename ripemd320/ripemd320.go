@@ -9,6 +9,8 @@ package ripemd320
 // and Bart Preneel with specifications available at:
 // https://homes.esat.kuleuven.be/~bosselae/ripemd/rmd320.txt.
 import (
+	"encoding/binary"
+	"errors"
 	"hash"
 )
 
@@ -48,11 +50,49 @@ func (d *digest) Reset() {
 
 // New returns a new hash.Hash computing the checksum.
 func New() hash.Hash {
+	selfTest.MustCheck()
 	result := new(digest)
 	result.Reset()
 	return result
 }
 
+const (
+	magic         = "rmd320\x01"
+	marshaledSize = len(magic) + 10*4 + BlockSize + 8
+)
+
+// MarshalBinary encodes the partial evaluation of the checksum, so
+// that hashing can be resumed with UnmarshalBinary.
+func (d *digest) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, marshaledSize)
+	b = append(b, magic...)
+	for _, s := range d.s {
+		b = binary.BigEndian.AppendUint32(b, s)
+	}
+	b = append(b, d.x[:d.nx]...)
+	b = b[:len(b)+len(d.x)-d.nx] // already zero
+	b = binary.BigEndian.AppendUint64(b, d.tc)
+	return b, nil
+}
+
+func (d *digest) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("ripemd320: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize {
+		return errors.New("ripemd320: invalid hash state size")
+	}
+	b = b[len(magic):]
+	for i := range d.s {
+		d.s[i] = binary.BigEndian.Uint32(b)
+		b = b[4:]
+	}
+	b = b[copy(d.x[:], b):]
+	d.tc = binary.BigEndian.Uint64(b)
+	d.nx = int(d.tc % BlockSize)
+	return nil
+}
+
 func (d *digest) Size() int { return Size }
 
 func (d *digest) BlockSize() int { return BlockSize }
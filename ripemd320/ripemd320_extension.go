@@ -0,0 +1,49 @@
+package ripemd320
+
+// Length extension of RIPEMD-320, for auditing legacy H(secret || message)
+// MACs. RIPEMD-320 is a plain Merkle-Damgard construction: its digest is
+// the full chaining state, so anyone knowing H(m) and len(m) can
+// compute H(m || padding || suffix) without knowing m.
+import (
+	"errors"
+	"hash"
+)
+
+// NewFromDigest returns a hash.Hash resuming from sum, the published digest of
+// an unknown message of origLen bytes, together with the glue padding
+// that the original computation appended to that message.
+//
+// Writing a suffix to the returned hash and calling Sum yields the
+// digest of message || glue || suffix. This is an attack primitive,
+// meant only for demonstrating the forgery against systems under
+// test; never use RIPEMD-320 as a MAC this way.
+func NewFromDigest(sum []byte, origLen uint64) (hash.Hash, []byte, error) {
+	if len(sum) != Size {
+		return nil, nil, errors.New("ripemd320: invalid digest length")
+	}
+	if err := selfTest.Check(); err != nil {
+		return nil, nil, err
+	}
+	glue := padding(origLen)
+	d := new(digest)
+	for i := range d.s {
+		d.s[i] = uint32(sum[i*4]) | uint32(sum[i*4+1])<<8 |
+			uint32(sum[i*4+2])<<16 | uint32(sum[i*4+3])<<24
+	}
+	d.tc = origLen + uint64(len(glue))
+	return d, glue, nil
+}
+
+// padding returns the bytes appended by Sum to a message of n bytes.
+func padding(n uint64) []byte {
+	pad := make([]byte, 0, 2*BlockSize)
+	pad = append(pad, 0x80)
+	for (n+uint64(len(pad)))%BlockSize != 56 {
+		pad = append(pad, 0)
+	}
+	n <<= 3
+	for i := uint(0); i < 8; i++ {
+		pad = append(pad, byte(n>>(8*i)))
+	}
+	return pad
+}
@@ -0,0 +1,29 @@
+package ripemd320
+
+// Known-answer test run before first use, see package selftest.
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+var selfTest = selftest.Register("RIPEMD-320", func() error {
+	vectors := []struct {
+		input, digest string
+	}{
+		{"abc", "de4c01b3054f8930a79d09ae738e92301e5a17085beffdc1b8d116713e74f82fa942d64cdbc4682d"},
+		{strings.Repeat("1234567890", 8), "557888af5f6d8ed62ab66945c6d2a0a47ecd5341e915eb8fea1d0524955f825dc717e4a008ab2d42"},
+	}
+	for _, v := range vectors {
+		// not through New, which waits for this test.
+		d := new(digest)
+		d.Reset()
+		d.Write([]byte(v.input))
+		if hex.EncodeToString(d.Sum(nil)) != v.digest {
+			return errors.New("wrong digest")
+		}
+	}
+	return nil
+})
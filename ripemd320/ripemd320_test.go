@@ -1,10 +1,16 @@
 package ripemd320
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/hex"
+	"errors"
 	"hash"
 	"strings"
 	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/hashtest"
+	"github.com/y3sh/go-legacy-crypto/selftest"
 )
 
 func TestRipemd320(t *testing.T) {
@@ -29,3 +35,96 @@ func assert320Hash(t *testing.T, input, expectedOutput string) {
 		t.Errorf("Expected %v, got %v", expectedOutput, actual)
 	}
 }
+
+func TestMarshalBinary(t *testing.T) {
+	message := []byte(strings.Repeat("abcdefghijklmnopqrstuvwxyz", 10))
+	expected := New()
+	expected.Write(message)
+
+	for _, split := range []int{0, 1, 63, 64, 65, 100, len(message)} {
+		h := New()
+		h.Write(message[:split])
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write([]byte("garbage"))
+
+		resumed := New()
+		if err := resumed.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+			t.Fatal(err)
+		}
+		resumed.Write(message[split:])
+		if !bytes.Equal(resumed.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("Split at %d: Expected %x, got %x", split, expected.Sum(nil), resumed.Sum(nil))
+		}
+	}
+
+	state, _ := New().(encoding.BinaryMarshaler).MarshalBinary()
+	h := New().(encoding.BinaryUnmarshaler)
+	if err := h.UnmarshalBinary(state[:len(state)-1]); err == nil {
+		t.Errorf("Expected an error for a truncated state")
+	}
+	state[0] ^= 1
+	if err := h.UnmarshalBinary(state); err == nil {
+		t.Errorf("Expected an error for a state with an invalid identifier")
+	}
+}
+
+func TestNewFromDigest(t *testing.T) {
+	secret, message, suffix := []byte("secret key"), []byte("user=guest"), []byte(";admin=true")
+	mac := New()
+	mac.Write(secret)
+	mac.Write(message)
+
+	forger, glue, err := NewFromDigest(mac.Sum(nil), uint64(len(secret)+len(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forger.Write(suffix)
+
+	for _, n := range []int{0, 55, 56, 64, 119} {
+		if l := n + len(padding(uint64(n))); l%BlockSize != 0 {
+			t.Errorf("Expected padding of %d bytes to end on a block boundary, got %d", n, l)
+		}
+	}
+	expected := New()
+	expected.Write(secret)
+	expected.Write(message)
+	expected.Write(glue)
+	expected.Write(suffix)
+	if !bytes.Equal(forger.Sum(nil), expected.Sum(nil)) {
+		t.Errorf("Expected forged digest %x, got %x", expected.Sum(nil), forger.Sum(nil))
+	}
+	if _, _, err := NewFromDigest(make([]byte, Size-1), 0); err == nil {
+		t.Errorf("Expected an error for a short digest")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	hashtest.TestHash(t, New)
+}
+
+func TestNESSIE(t *testing.T) {
+	// the header of the file says where its vectors come from.
+	hashtest.TestNESSIE(t, New, "testdata/nessie.txt")
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := selftest.RunAll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	saved := selfTest
+	defer func() { selfTest = saved }()
+	selfTest = selftest.New("broken RIPEMD-320", func() error { return errors.New("broken") })
+	if _, _, err := NewFromDigest(make([]byte, Size), 0); err == nil {
+		t.Errorf("Expected NewFromDigest to fail")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected New to panic")
+		}
+	}()
+	New()
+}
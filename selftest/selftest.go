@@ -0,0 +1,151 @@
+// Package selftest runs the known-answer tests of the algorithms of this
+// module before they are first used.
+//
+// Each algorithm registers a known-answer test when its package is
+// initialized. The test runs the first time the algorithm is
+// constructed, or for every algorithm at once when RunAll is called.
+// If it fails, the constructors of the algorithm return the failure, or
+// panic with it where they cannot return an error, on every call.
+package selftest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// A KATError reports the failure of the known-answer test of an
+// algorithm.
+type KATError struct {
+	Algorithm string
+	Err       error
+}
+
+func (e *KATError) Error() string {
+	return "selftest: " + e.Algorithm + " known-answer test failed: " + e.Err.Error()
+}
+
+func (e *KATError) Unwrap() error { return e.Err }
+
+// A Test is the registered known-answer test of an algorithm.
+type Test struct {
+	name string
+	kat  func() error
+	once sync.Once
+	err  error
+	ran  atomic.Bool // set once err is final
+}
+
+var (
+	mu    sync.Mutex
+	tests = map[string]*Test{}
+)
+
+// Register records kat as the known-answer test of the algorithm name.
+// kat must not construct the algorithm through the constructors that
+// call Check. Register panics if name is already registered.
+func Register(name string, kat func() error) *Test {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := tests[name]; ok {
+		panic("selftest: " + name + " registered twice")
+	}
+	t := New(name, kat)
+	tests[name] = t
+	return t
+}
+
+// New returns a known-answer test that is not registered, and so is
+// neither run by RunAll nor reported by Results. It lets the tests of
+// an algorithm swap in a failing known-answer test without leaving it
+// behind in the registry.
+func New(name string, kat func() error) *Test {
+	return &Test{name: name, kat: kat}
+}
+
+// unregister removes name from the registry, for the tests of this
+// package.
+func unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(tests, name)
+}
+
+// Name returns the name of the algorithm.
+func (t *Test) Name() string { return t.name }
+
+// Check runs the test the first time it is called, and returns a
+// *KATError if it failed, then and ever after.
+func (t *Test) Check() error {
+	t.once.Do(func() {
+		if err := run(t.kat); err != nil {
+			t.err = &KATError{Algorithm: t.name, Err: err}
+		}
+		t.ran.Store(true)
+	})
+	return t.err
+}
+
+// MustCheck is like Check, but panics if the test failed. It is meant
+// for constructors that cannot return an error.
+func (t *Test) MustCheck() {
+	if err := t.Check(); err != nil {
+		panic(err)
+	}
+}
+
+// run turns a panicking test into a failed one.
+func run(kat func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return kat()
+}
+
+// RunAll runs every registered test that has not run yet and returns
+// the failures of all of them, joined.
+func RunAll() error {
+	var errs []error
+	for _, t := range registered() {
+		if err := t.Check(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// A Result is the state of the known-answer test of an algorithm.
+type Result struct {
+	Algorithm string
+	Ran       bool
+	Err       error // nil unless the test ran and failed
+}
+
+// Results reports the state of every registered test, sorted by
+// algorithm name, without running any.
+func Results() []Result {
+	var results []Result
+	for _, t := range registered() {
+		r := Result{Algorithm: t.name}
+		if t.ran.Load() {
+			r.Ran, r.Err = true, t.err
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func registered() []*Test {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]*Test, 0, len(tests))
+	for _, t := range tests {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
@@ -0,0 +1,115 @@
+package selftest
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// register registers a test for the duration of t.
+func register(t *testing.T, name string, kat func() error) *Test {
+	test := Register(name, kat)
+	t.Cleanup(func() { unregister(name) })
+	return test
+}
+
+func TestCheck(t *testing.T) {
+	runs := 0
+	passing := register(t, "passing", func() error {
+		runs++
+		return nil
+	})
+	for i := 0; i < 3; i++ {
+		if err := passing.Check(); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	}
+	if runs != 1 {
+		t.Errorf("Expected the test to run once, got %d runs", runs)
+	}
+
+	failure := errors.New("wrong digest")
+	failing := register(t, "failing", func() error { return failure })
+	err := failing.Check()
+	var katErr *KATError
+	if !errors.As(err, &katErr) || katErr.Algorithm != "failing" || !errors.Is(err, failure) {
+		t.Errorf("Expected a KATError for failing, got %v", err)
+	}
+	if err2 := failing.Check(); err2 != err {
+		t.Errorf("Expected the same error on every call, got %v", err2)
+	}
+
+	panicking := register(t, "panicking", func() error { panic("index out of range") })
+	if err := panicking.Check(); err == nil {
+		t.Errorf("Expected a panicking test to fail")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected MustCheck to panic")
+		}
+	}()
+	failing.MustCheck()
+}
+
+func TestRunAll(t *testing.T) {
+	lazy := register(t, "lazy", func() error { return errors.New("lazy failure") })
+	register(t, "lazy-ok", func() error { return nil })
+	for _, r := range Results() {
+		if r.Algorithm == "lazy" && (r.Ran || r.Err != nil) {
+			t.Errorf("Expected lazy not to have run, got %+v", r)
+		}
+	}
+	err := RunAll()
+	if !errors.Is(err, lazy.Check()) {
+		t.Errorf("Expected RunAll to report lazy, got %v", err)
+	}
+	for _, r := range Results() {
+		if !r.Ran {
+			t.Errorf("Expected %s to have run", r.Algorithm)
+		}
+		if (r.Err != nil) != (r.Algorithm == "lazy") {
+			t.Errorf("%s: unexpected result %v", r.Algorithm, r.Err)
+		}
+	}
+}
+
+func TestConcurrentCheck(t *testing.T) {
+	test := register(t, "concurrent", func() error { return nil })
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			test.Check()
+			Results()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRegisterTwice(t *testing.T) {
+	register(t, "twice", func() error { return nil })
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a second registration to panic")
+		}
+	}()
+	Register("twice", func() error { return nil })
+}
+
+func TestNew(t *testing.T) {
+	test := New("unregistered", func() error { return errors.New("broken") })
+	if err := test.Check(); err == nil {
+		t.Errorf("Expected the test to fail")
+	}
+	if err := RunAll(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	for _, r := range Results() {
+		if r.Algorithm == "unregistered" {
+			t.Errorf("Expected unregistered not to be reported")
+		}
+	}
+	Register("unregistered", func() error { return nil })
+	unregister("unregistered")
+}
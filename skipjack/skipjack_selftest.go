@@ -0,0 +1,31 @@
+package skipjack
+
+// Known-answer test run before first use, see package selftest.
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+var selfTest = selftest.Register("SKIPJACK", func() error {
+	key, _ := hex.DecodeString("00998877665544332211")
+	plain, _ := hex.DecodeString("33221100ddccbbaa")
+	expected, _ := hex.DecodeString("2587cae27a12d300")
+	// not through NewCipher, which waits for this test.
+	c, err := newCipher(key)
+	if err != nil {
+		return err
+	}
+	var out [BlockSize]byte
+	c.Encrypt(out[:], plain)
+	if !bytes.Equal(out[:], expected) {
+		return errors.New("wrong encryption")
+	}
+	c.Decrypt(out[:], out[:])
+	if !bytes.Equal(out[:], plain) {
+		return errors.New("wrong decryption")
+	}
+	return nil
+})
@@ -0,0 +1,117 @@
+package skipjack
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+func TestSkipjack(t *testing.T) {
+	// SKIPJACK and KEA Algorithm Specifications, section 4.
+	assertEncrypt(t, "00998877665544332211", "33221100ddccbbaa", "2587cae27a12d300")
+}
+
+func assertEncrypt(t *testing.T, key, plain, expected string) {
+	k, _ := hex.DecodeString(key)
+	p, _ := hex.DecodeString(plain)
+	c, err := NewCipher(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, BlockSize)
+	c.Encrypt(out, p)
+	if actual := hex.EncodeToString(out); actual != expected {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	c.Decrypt(out, out)
+	if actual := hex.EncodeToString(out); actual != plain {
+		t.Errorf("Expected %v, got %v", plain, actual)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	key := make([]byte, KeySize)
+	block := make([]byte, BlockSize)
+	out := make([]byte, BlockSize)
+	for i := 0; i < 1000; i++ {
+		r.Read(key)
+		r.Read(block)
+		c, err := NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Encrypt(out, block)
+		c.Decrypt(out, out)
+		if !bytes.Equal(out, block) {
+			t.Fatalf("Key %x: Expected %x, got %x", key, block, out)
+		}
+	}
+}
+
+func TestG(t *testing.T) {
+	c, _ := newCipher([]byte("0123456789"))
+	for k := 0; k < rounds; k++ {
+		seen := make(map[uint16]bool)
+		for w := 0; w < 1<<16; w++ {
+			g := c.g(k, uint16(w))
+			if c.gInv(k, g) != uint16(w) {
+				t.Fatalf("Step %d: Expected %d, got %d", k, w, c.gInv(k, g))
+			}
+			seen[g] = true
+		}
+		if len(seen) != 1<<16 {
+			t.Fatalf("Step %d: Expected a permutation, got %d values", k, len(seen))
+		}
+	}
+}
+
+func TestCBC(t *testing.T) {
+	c, _ := NewCipher([]byte("0123456789"))
+	iv := make([]byte, BlockSize)
+	plain := []byte("Fortezza-era archive, 4 blocks!!")
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(c, iv).CryptBlocks(encrypted, plain)
+	decrypted := make([]byte, len(plain))
+	cipher.NewCBCDecrypter(c, iv).CryptBlocks(decrypted, encrypted)
+	if !bytes.Equal(decrypted, plain) {
+		t.Errorf("Expected %q, got %q", plain, decrypted)
+	}
+}
+
+func TestKeySize(t *testing.T) {
+	for _, n := range []int{0, 8, 9, 11, 16} {
+		_, err := NewCipher(make([]byte, n))
+		var sizeErr KeySizeError
+		if !errors.As(err, &sizeErr) || int(sizeErr) != n {
+			t.Errorf("Expected KeySizeError(%d), got %v", n, err)
+		}
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	c, _ := NewCipher([]byte("0123456789"))
+	buf := make([]byte, BlockSize)
+	b.SetBytes(BlockSize)
+	for i := 0; i < b.N; i++ {
+		c.Encrypt(buf, buf)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := selftest.RunAll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	saved := selfTest
+	defer func() { selfTest = saved }()
+	selfTest = selftest.New("broken SKIPJACK", func() error { return errors.New("broken") })
+	if _, err := NewCipher(make([]byte, KeySize)); err == nil {
+		t.Errorf("Expected NewCipher to fail")
+	}
+}
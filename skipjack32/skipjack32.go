@@ -19,15 +19,67 @@ type SkipJack32 struct {
 	keyAsciiValues [keyLength]uint32
 	byteOrder      binary.ByteOrder
 	fTable         [fTableLength]uint32
+	immutable      bool // returned by NewSkip32
 }
 
+// An Option configures a SkipJack32 returned by NewSkip32.
+type Option func(*SkipJack32)
+
+// WithByteOrder sets the order in which the bytes of a number are fed to
+// the cipher: binary.LittleEndian, the default, binary.BigEndian, or
+// binary.NativeEndian, which stands for one of them. NewSkip32 fails for
+// any other order.
+func WithByteOrder(byteOrder binary.ByteOrder) Option {
+	return func(s *SkipJack32) {
+		s.byteOrder = byteOrder
+	}
+}
+
+// NewSkip32 returns a SkipJack32 keyed with key, which must be exactly 10
+// bytes long. Unlike a SkipJack32 set up with Init, the result cannot be
+// changed afterwards and is safe for concurrent use.
+func NewSkip32(key []byte, opts ...Option) (*SkipJack32, error) {
+	if err := selfTest.Check(); err != nil {
+		return nil, err
+	}
+	if len(key) != keyLength {
+		return nil, fmt.Errorf("error: expected key of %d bytes, actual: %d", keyLength, len(key))
+	}
+
+	s := &SkipJack32{byteOrder: binary.LittleEndian, fTable: getInitialSkipjackFTable()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	byteOrder, err := checkByteOrder(s.byteOrder)
+	if err != nil {
+		return nil, err
+	}
+	s.byteOrder = byteOrder
+	for i, b := range key {
+		s.keyAsciiValues[i] = uint32(b)
+	}
+	s.immutable = true
+	return s, nil
+}
+
+// Init keys s with the first 10 bytes of seed; the rest of seed is
+// ignored. Use NewSkip32 for binary keys.
 func (s *SkipJack32) Init(seed string, byteOrder binary.ByteOrder) error {
+	if err := selfTest.Check(); err != nil {
+		return err
+	}
+
+	if s.immutable {
+		return fmt.Errorf("error: cannot Init a SkipJack32 returned by NewSkip32")
+	}
+
 	s.fTable = getInitialSkipjackFTable()
 
-	s.byteOrder = byteOrder
-	if s.byteOrder == nil {
-		return fmt.Errorf("error: expected byteOrder but nil supplied")
+	byteOrder, err := checkByteOrder(byteOrder)
+	if err != nil {
+		return err
 	}
+	s.byteOrder = byteOrder
 
 	keyBytes := []byte(seed)
 	if len(seed) < keyLength {
@@ -42,21 +94,31 @@ func (s *SkipJack32) Init(seed string, byteOrder binary.ByteOrder) error {
 	return nil
 }
 
-func (s *SkipJack32) g(key [keyLength]uint32, k uint32, w uint32) uint32 {
-	if k < 0 {
-		panic("Negative k values will cause range errors.")
-	}
-
-	// Prevent uint32 overflow
-	// 2^32 positive uint32's minus the 2^2 `k4` = 2^29
-	if k >= 1<<30 {
-		panic("K value too high, will overflow to small integers.")
+// checkByteOrder returns binary.LittleEndian or binary.BigEndian for
+// byteOrder, the only orders Process knows, resolving
+// binary.NativeEndian to the order of the machine.
+func checkByteOrder(byteOrder binary.ByteOrder) (binary.ByteOrder, error) {
+	switch byteOrder {
+	case nil:
+		return nil, fmt.Errorf("error: expected byteOrder but nil supplied")
+	case binary.LittleEndian, binary.BigEndian:
+		return byteOrder, nil
+	case binary.NativeEndian:
+		if binary.NativeEndian.Uint16([]byte{1, 0}) == 1 {
+			return binary.LittleEndian, nil
+		}
+		return binary.BigEndian, nil
 	}
+	return nil, fmt.Errorf("error: unsupported byteOrder %v", byteOrder)
+}
 
+func (s *SkipJack32) g(key [keyLength]uint32, k uint32, w uint32) uint32 {
 	g1 := (w >> 8) & 0xff
 	g2 := w & 0xff
 	var g3, g4, g5, g6 uint32
-	k4 := 4 * k
+	// 4*k modulo keyLength only depends on k modulo 5; reducing k first
+	// keeps 4*k from overflowing for any k.
+	k4 := 4 * (k % 5)
 
 	g3 = s.fTable[g2^s.keyAsciiValues[k4%keyLength]] ^ g1
 	g4 = s.fTable[g3^s.keyAsciiValues[(k4+1)%keyLength]] ^ g2
@@ -107,6 +169,16 @@ func (s *SkipJack32) Process(num32 uint32, encrypt bool) uint32 {
 	return (((wr >> 8) & 0xff) + ((wr << 8) & 0xff00) + ((wl << 8) & 0xff0000) + (wl << 24)) & 0xffffffff
 }
 
+// Encrypt returns the encryption of num32.
+func (s *SkipJack32) Encrypt(num32 uint32) uint32 {
+	return s.ProcessUnrolled(num32, true)
+}
+
+// Decrypt returns the decryption of num32.
+func (s *SkipJack32) Decrypt(num32 uint32) uint32 {
+	return s.ProcessUnrolled(num32, false)
+}
+
 func (s *SkipJack32) ProcessUnrolled(num32 uint32, encrypt bool) uint32 {
 	// k = round number
 	// i = round counter
@@ -0,0 +1,64 @@
+package mask
+
+// Derivation of SKIP32 keys from passphrases of any length with PBKDF2,
+// so that the whole passphrase, not only its first 10 bytes, selects the
+// permutation, and so that one passphrase gives unrelated keys in
+// different contexts.
+import (
+	"fmt"
+
+	"github.com/y3sh/go-legacy-crypto/kdf"
+)
+
+// DefaultKeyIterations is the number of PBKDF2 iterations of DeriveKey
+// when KeyParams leaves it zero. It will not change, so that derived
+// keys stay the same across releases.
+const DefaultKeyIterations = 100000
+
+// KeyParams configures DeriveKey. Every party deriving the same key must
+// use the same parameters.
+type KeyParams struct {
+	PRF        kdf.PRF // hash of the HMAC; kdf.Whirlpool if empty
+	Iterations int     // DefaultKeyIterations if zero
+}
+
+// keySaltPrefix separates SKIP32 keys from other keys derived from the
+// same passphrase.
+const keySaltPrefix = "SKIP32\x00"
+
+// DeriveKey derives a 10-byte key for NewSkip32 from passphrase and a
+// context label naming what the key obfuscates, e.g. "customer-ids".
+// The key is PBKDF2 over HMAC-p.PRF of passphrase, with "SKIP32", a zero
+// byte and context as the salt.
+//
+// The salt is fixed so that all services derive the same key; it is not
+// secret, and all of the strength of the key comes from passphrase.
+func DeriveKey(passphrase []byte, context string, p KeyParams) ([]byte, error) {
+	if p.PRF == "" {
+		p.PRF = kdf.Whirlpool
+	}
+	if p.Iterations == 0 {
+		p.Iterations = DefaultKeyIterations
+	}
+	h, err := p.PRF.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if p.Iterations < 0 {
+		return nil, fmt.Errorf("error: expected positive iterations, actual: %d", p.Iterations)
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("error: expected passphrase but empty supplied")
+	}
+	return kdf.Key(h, passphrase, []byte(keySaltPrefix+context), p.Iterations, keyLength), nil
+}
+
+// NewSkip32FromPassphrase returns a SkipJack32 keyed with the key
+// DeriveKey derives from passphrase and context.
+func NewSkip32FromPassphrase(passphrase []byte, context string, p KeyParams, opts ...Option) (*SkipJack32, error) {
+	key, err := DeriveKey(passphrase, context, p)
+	if err != nil {
+		return nil, err
+	}
+	return NewSkip32(key, opts...)
+}
@@ -0,0 +1,39 @@
+package mask
+
+// Known-answer test run before first use, see package selftest.
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+var selfTest = selftest.Register("SKIP32", func() error {
+	// not through Init, which waits for this test.
+	s := SkipJack32{fTable: getInitialSkipjackFTable(), byteOrder: binary.LittleEndian}
+	for i, b := range []byte("SECRET_KEY") {
+		s.keyAsciiValues[i] = uint32(b)
+	}
+	vectors := []struct {
+		plain, cipher uint32
+	}{
+		{0, 4130141102},
+		{1, 352711532},
+		{2, 2049254042},
+	}
+	for _, v := range vectors {
+		if s.Process(v.plain, true) != v.cipher || s.ProcessUnrolled(v.plain, true) != v.cipher {
+			return errors.New("wrong encryption")
+		}
+		if s.Process(v.cipher, false) != v.plain || s.ProcessUnrolled(v.cipher, false) != v.plain {
+			return errors.New("wrong decryption")
+		}
+	}
+	if s.process(0, 3, wordBits, rounds48, true) != 218566128148629 ||
+		s.process(218566128148629, 3, wordBits, rounds48, false) != 0 ||
+		s.process(0, 4, wordBits, rounds64, true) != 9106053643790359331 ||
+		s.process(9106053643790359331, 4, wordBits, rounds64, false) != 0 {
+		return errors.New("wrong 48-bit or 64-bit permutation")
+	}
+	return nil
+})
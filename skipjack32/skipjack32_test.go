@@ -2,9 +2,16 @@ package mask
 
 import (
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"math"
+	"math/bits"
 	"reflect"
+	"sync"
 	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/kdf"
+	"github.com/y3sh/go-legacy-crypto/selftest"
 )
 
 func TestSkipJack32InitClassic(t *testing.T) {
@@ -31,6 +38,10 @@ func TestG(t *testing.T) {
 	AssertEqualsU32(t, 21650, skipJack.g(skipJack.keyAsciiValues, 1, 2))
 	AssertEqualsU32(t, 44711, skipJack.g(skipJack.keyAsciiValues, 0, math.MaxUint32))
 	AssertEqualsU32(t, 8379, skipJack.g(skipJack.keyAsciiValues, 1<<30-1, 0))
+
+	// large round numbers used to panic.
+	AssertEqualsU32(t, skipJack.g(skipJack.keyAsciiValues, 0, 7), skipJack.g(skipJack.keyAsciiValues, 1<<30+1, 7))
+	AssertEqualsU32(t, skipJack.g(skipJack.keyAsciiValues, 0, 7), skipJack.g(skipJack.keyAsciiValues, math.MaxUint32, 7))
 }
 
 func TestProcess(t *testing.T) {
@@ -57,8 +68,329 @@ func TestProcessUnrolled(t *testing.T) {
 	AssertEqualsU32(t, 4263131063, skipJack.ProcessUnrolled(2, false))
 }
 
+func TestNewSkip32(t *testing.T) {
+	skipJack, err := NewSkip32([]byte("SECRET_KEY"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	AssertEqualsU32(t, 4130141102, skipJack.Encrypt(0))
+	AssertEqualsU32(t, 3101515088, skipJack.Decrypt(0))
+	AssertEqualsU32(t, 352711532, skipJack.Encrypt(1))
+	AssertEqualsU32(t, 2049254042, skipJack.Encrypt(2))
+	AssertEqualsU32(t, 4263131063, skipJack.Decrypt(2))
+
+	classic := SkipJack32{}
+	_ = classic.Init("SECRET_KEY", binary.BigEndian)
+	bigEndian, err := NewSkip32([]byte("SECRET_KEY"), WithByteOrder(binary.BigEndian))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, n := range []uint32{0, 1, 0xdeadbeef, math.MaxUint32} {
+		AssertEqualsU32(t, classic.Process(n, true), bigEndian.Encrypt(n))
+	}
+
+	// any byte value, not only ASCII.
+	binaryKey, err := NewSkip32([]byte{0x00, 0xff, 0x80, 0x7f, 0x01, 0xfe, 0x55, 0xaa, 0x00, 0xff})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, n := range []uint32{0, 1, 0xdeadbeef, math.MaxUint32} {
+		AssertEqualsU32(t, n, binaryKey.Decrypt(binaryKey.Encrypt(n)))
+	}
+	if binaryKey.Encrypt(0) == skipJack.Encrypt(0) {
+		t.Errorf("Expected different keys to give different permutations")
+	}
+}
+
+func TestNewSkip32Errors(t *testing.T) {
+	for _, key := range []string{"", "SECRET", "SECRET_KE", "SECRET_KEY!", "SECRET_KEY_AND_MORE"} {
+		if _, err := NewSkip32([]byte(key)); err == nil {
+			t.Errorf("%q: Expected an error for a key of %d bytes", key, len(key))
+		}
+	}
+	if _, err := NewSkip32([]byte("SECRET_KEY"), WithByteOrder(nil)); err == nil {
+		t.Errorf("Expected an error for a nil byte order")
+	}
+
+	skipJack, _ := NewSkip32([]byte("SECRET_KEY"))
+	if err := skipJack.Init("OTHER_SECRET", binary.LittleEndian); err == nil {
+		t.Errorf("Expected Init to fail")
+	}
+	AssertEqualsU32(t, 4130141102, skipJack.Encrypt(0))
+}
+
+// swappedOrder is a byte order that SkipJack32 does not know.
+type swappedOrder struct{ binary.ByteOrder }
+
+func TestNewSkip32ByteOrders(t *testing.T) {
+	native, err := NewSkip32([]byte("SECRET_KEY"), WithByteOrder(binary.NativeEndian))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	concrete := binary.ByteOrder(binary.LittleEndian)
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		concrete = binary.BigEndian
+	}
+	expected, _ := NewSkip32([]byte("SECRET_KEY"), WithByteOrder(concrete))
+	for x := uint32(0); x < 1000; x++ {
+		AssertEqualsU32(t, expected.Encrypt(x), native.Encrypt(x))
+		AssertEqualsU32(t, x, native.Decrypt(native.Encrypt(x)))
+	}
+
+	unknown := swappedOrder{binary.BigEndian}
+	if _, err := NewSkip32([]byte("SECRET_KEY"), WithByteOrder(unknown)); err == nil {
+		t.Errorf("Expected an error for an unknown byte order")
+	}
+	skipJack := SkipJack32{}
+	if err := skipJack.Init("SECRET_KEY", unknown); err == nil {
+		t.Errorf("Expected Init to fail for an unknown byte order")
+	}
+	if err := skipJack.Init("SECRET_KEY", binary.NativeEndian); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	AssertEqualsU32(t, expected.Encrypt(7), skipJack.Process(7, true))
+}
+
+func TestNewSkip32Concurrent(t *testing.T) {
+	// meaningful under the race detector.
+	skipJack, err := NewSkip32([]byte("SECRET_KEY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := make([]uint32, 1000)
+	for i := range expected {
+		expected[i] = skipJack.Encrypt(uint32(i))
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, e := range expected {
+				if skipJack.Encrypt(uint32(i)) != e || skipJack.Decrypt(e) != uint32(i) {
+					t.Errorf("Expected %v for %v", e, i)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDeriveKey(t *testing.T) {
+	// Whirlpool and RIPEMD-160 keys checked with "openssl kdf PBKDF2",
+	// RIPEMD-320 keys with an independent implementation.
+	passphrase := []byte("correct horse battery staple")
+	vectors := []struct {
+		context  string
+		p        KeyParams
+		expected string
+	}{
+		{"customer-ids", KeyParams{Iterations: 1}, "e7a6b322ff0f13728164"},
+		{"customer-ids", KeyParams{Iterations: 1000}, "e0f5c407efa253f63e71"},
+		{"customer-ids", KeyParams{PRF: kdf.Whirlpool, Iterations: 1000}, "e0f5c407efa253f63e71"},
+		{"customer-ids", KeyParams{}, "5bb2281c8f21bf803a03"},
+		{"order-ids", KeyParams{Iterations: 1000}, "9fb26424ef9caa13a89c"},
+		{"customer-ids", KeyParams{PRF: kdf.RIPEMD160, Iterations: 1000}, "440224620806d778beeb"},
+		{"customer-ids", KeyParams{PRF: kdf.RIPEMD320, Iterations: 1000}, "8dc0774494b335e7654a"},
+	}
+	for _, v := range vectors {
+		key, err := DeriveKey(passphrase, v.context, v.p)
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+			continue
+		}
+		if actual := hex.EncodeToString(key); actual != v.expected {
+			t.Errorf("%s %v: Expected %v, got %v", v.context, v.p, v.expected, actual)
+		}
+	}
+
+	// passphrases sharing their first 10 bytes give different keys.
+	a, _ := NewSkip32FromPassphrase([]byte("SECRET_KEY one"), "ids", KeyParams{Iterations: 10})
+	b, _ := NewSkip32FromPassphrase([]byte("SECRET_KEY two"), "ids", KeyParams{Iterations: 10})
+	if a == nil || b == nil || a.Encrypt(0) == b.Encrypt(0) {
+		t.Errorf("Expected different permutations")
+	}
+}
+
+func TestDeriveKeyErrors(t *testing.T) {
+	if _, err := DeriveKey(nil, "ids", KeyParams{}); err == nil {
+		t.Errorf("Expected an error for an empty passphrase")
+	}
+	if _, err := DeriveKey([]byte("pass"), "ids", KeyParams{Iterations: -1}); err == nil {
+		t.Errorf("Expected an error for negative iterations")
+	}
+	if _, err := NewSkip32FromPassphrase([]byte("pass"), "ids", KeyParams{PRF: "md5"}); err == nil {
+		t.Errorf("Expected an error for an unsupported PRF")
+	}
+}
+
+func TestSkip48(t *testing.T) {
+	// vectors checked with an independent implementation.
+	skipJack, err := NewSkip48([]byte("SECRET_KEY"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	vectors := []struct {
+		plain, cipher uint64
+	}{
+		{0, 218566128148629},
+		{1, 249930428657839},
+		{2, 242335932764788},
+		{1 << 32, 46343704055097},
+		{0xdeadbeefcafe, 19538453580627},
+		{MaxUint48, 218433976451837},
+	}
+	for _, v := range vectors {
+		if actual, err := skipJack.Encrypt(v.plain); err != nil || actual != v.cipher {
+			t.Errorf("Expected %v, got %v (%v)", v.cipher, actual, err)
+		}
+		if actual, err := skipJack.Decrypt(v.cipher); err != nil || actual != v.plain {
+			t.Errorf("Expected %v, got %v (%v)", v.plain, actual, err)
+		}
+		if actual, err := skipJack.EncryptInt64(int64(v.plain)); err != nil || actual != int64(v.cipher) {
+			t.Errorf("Expected %v, got %v (%v)", v.cipher, actual, err)
+		}
+		if actual, err := skipJack.DecryptInt64(int64(v.cipher)); err != nil || actual != int64(v.plain) {
+			t.Errorf("Expected %v, got %v (%v)", v.plain, actual, err)
+		}
+	}
+
+	if _, err := skipJack.Encrypt(MaxUint48 + 1); err == nil {
+		t.Errorf("Expected an error for a number of more than 48 bits")
+	}
+	if _, err := skipJack.Decrypt(math.MaxUint64); err == nil {
+		t.Errorf("Expected an error for a number of more than 48 bits")
+	}
+	for _, x := range []int64{-1, math.MinInt64, MaxUint48 + 1, math.MaxInt64} {
+		if _, err := skipJack.EncryptInt64(x); err == nil {
+			t.Errorf("%d: Expected an error", x)
+		}
+		if _, err := skipJack.DecryptInt64(x); err == nil {
+			t.Errorf("%d: Expected an error", x)
+		}
+	}
+	if _, err := NewSkip48([]byte("SECRET")); err == nil {
+		t.Errorf("Expected an error for a key of 6 bytes")
+	}
+}
+
+func TestSkip64(t *testing.T) {
+	// vectors checked with an independent implementation.
+	skipJack, err := NewSkip64([]byte("SECRET_KEY"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	vectors := []struct {
+		plain, cipher uint64
+	}{
+		{0, 9106053643790359331},
+		{1, 3986746870747512571},
+		{2, 16239457756226564842},
+		{1 << 32, 9255580852743867679},
+		{0xdeadbeefcafebabe, 2142836134442500033},
+		{math.MaxUint64, 12323444600769936987},
+	}
+	for _, v := range vectors {
+		if actual := skipJack.Encrypt(v.plain); actual != v.cipher {
+			t.Errorf("Expected %v, got %v", v.cipher, actual)
+		}
+		if actual := skipJack.Decrypt(v.cipher); actual != v.plain {
+			t.Errorf("Expected %v, got %v", v.plain, actual)
+		}
+	}
+
+	for _, x := range []int64{0, 42, -1, math.MinInt64, math.MaxInt64} {
+		if actual := skipJack.DecryptInt64(skipJack.EncryptInt64(x)); actual != x {
+			t.Errorf("Expected %v, got %v", x, actual)
+		}
+	}
+	if actual := skipJack.EncryptInt64(-1); actual != -6123299472939614629 {
+		t.Errorf("Expected %v, got %v", int64(-6123299472939614629), actual)
+	}
+
+	if _, err := NewSkip64([]byte("SECRET_KEY!")); err == nil {
+		t.Errorf("Expected an error for a key of 11 bytes")
+	}
+}
+
+func TestFeistelBijective(t *testing.T) {
+	// the 48-bit and 64-bit networks on words narrow enough to enumerate
+	// every input.
+	skipJack, _ := NewSkip32([]byte("SECRET_KEY"))
+	for _, c := range []struct {
+		n      int
+		width  uint
+		rounds int
+	}{
+		{3, 6, rounds48},
+		{4, 5, rounds64},
+	} {
+		size := uint64(1) << (uint(c.n) * c.width)
+		seen := make([]bool, size)
+		for x := uint64(0); x < size; x++ {
+			y := skipJack.process(x, c.n, c.width, c.rounds, true)
+			if y >= size || seen[y] {
+				t.Fatalf("%d words of %d bits: %d is not a permutation", c.n, c.width, y)
+			}
+			seen[y] = true
+			if back := skipJack.process(y, c.n, c.width, c.rounds, false); back != x {
+				t.Fatalf("%d words of %d bits: Expected %v, got %v", c.n, c.width, x, back)
+			}
+		}
+	}
+}
+
+func TestWideAvalanche(t *testing.T) {
+	// flipping one input bit flips half of the output bits on average.
+	s48, _ := NewSkip48([]byte("SECRET_KEY"))
+	s64, _ := NewSkip64([]byte("SECRET_KEY"))
+	encrypt48 := func(x uint64) uint64 {
+		y, _ := s48.Encrypt(x)
+		return y
+	}
+	for _, c := range []struct {
+		bits    int
+		encrypt func(uint64) uint64
+	}{
+		{48, encrypt48},
+		{64, s64.Encrypt},
+	} {
+		mask := uint64(math.MaxUint64) >> uint(64-c.bits)
+		flipped, samples := 0, 0
+		for i := uint64(0); i < 200; i++ {
+			x := (i * 0x9e3779b97f4a7c15) & mask
+			y := c.encrypt(x)
+			for b := 0; b < c.bits; b++ {
+				flipped += bits.OnesCount64(y ^ c.encrypt(x^1<<uint(b)))
+				samples++
+			}
+		}
+		if mean := float64(flipped) / float64(samples); math.Abs(mean-float64(c.bits)/2) > 0.5 {
+			t.Errorf("%d bits: Expected %v flipped bits on average, got %v", c.bits, c.bits/2, mean)
+		}
+	}
+}
+
 func AssertEqualsU32(t *testing.T, expected, actual uint32) {
 	if expected != actual {
 		t.Errorf("Expected %v, got %v", expected, actual)
 	}
 }
+
+func TestSelfTest(t *testing.T) {
+	if err := selftest.RunAll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	saved := selfTest
+	defer func() { selfTest = saved }()
+	selfTest = selftest.New("broken SKIP32", func() error { return errors.New("broken") })
+	skipJack := SkipJack32{}
+	if err := skipJack.Init("SECRET_KEY", binary.LittleEndian); err == nil {
+		t.Errorf("Expected Init to fail")
+	}
+	if _, err := NewSkip32([]byte("SECRET_KEY")); err == nil {
+		t.Errorf("Expected NewSkip32 to fail")
+	}
+}
@@ -0,0 +1,154 @@
+package mask
+
+//  48-bit and 64-bit permutations for ids that outgrow SKIP32. The number
+//  is split into three or four 16-bit words, most significant first, and
+//  permuted with a generalized (type-1) Feistel network on the same keyed
+//  g as SKIP32: each round XORs g of the first word and the round number
+//  into the second word, then rotates the words left by one.
+//
+//  Every word passes through g twelve times, as in the 24 rounds of
+//  SKIP32: that is 36 and 48 rounds, against 4 and 6 rounds for every
+//  output word to depend on every input word.
+//
+//  These are not standard algorithms and have no published test vectors.
+import (
+	"fmt"
+)
+
+const (
+	wordBits = 16
+	rounds48 = 3 * 12
+	rounds64 = 4 * 12
+
+	// MaxUint48 is the largest number permuted by SkipJack48.
+	MaxUint48 = 1<<48 - 1
+)
+
+// SkipJack48 permutes the numbers from 0 to MaxUint48. It is safe for
+// concurrent use.
+type SkipJack48 struct {
+	s *SkipJack32
+}
+
+// SkipJack64 permutes 64-bit numbers. It is safe for concurrent use.
+type SkipJack64 struct {
+	s *SkipJack32
+}
+
+// NewSkip48 returns a SkipJack48 keyed with key, which must be exactly 10
+// bytes long.
+func NewSkip48(key []byte) (*SkipJack48, error) {
+	s, err := NewSkip32(key)
+	if err != nil {
+		return nil, err
+	}
+	return &SkipJack48{s: s}, nil
+}
+
+// NewSkip64 returns a SkipJack64 keyed with key, which must be exactly 10
+// bytes long.
+func NewSkip64(key []byte) (*SkipJack64, error) {
+	s, err := NewSkip32(key)
+	if err != nil {
+		return nil, err
+	}
+	return &SkipJack64{s: s}, nil
+}
+
+// feistel permutes w, whose words are width bits wide, with the given
+// number of rounds. Widths below 16 bits only serve to test the network
+// on domains small enough to enumerate.
+func (s *SkipJack32) feistel(w []uint32, width uint, rounds int, encrypt bool) {
+	mask := uint32(1)<<width - 1
+	n := len(w)
+	if encrypt {
+		for k := 0; k < rounds; k++ {
+			w[1] ^= (s.g(s.keyAsciiValues, uint32(k), w[0]) ^ uint32(k)) & mask
+			w0 := w[0]
+			copy(w, w[1:])
+			w[n-1] = w0
+		}
+		return
+	}
+	for k := rounds - 1; k >= 0; k-- {
+		wn := w[n-1]
+		copy(w[1:], w[:n-1])
+		w[0] = wn
+		w[1] ^= (s.g(s.keyAsciiValues, uint32(k), w[0]) ^ uint32(k)) & mask
+	}
+}
+
+// process permutes the n words of width bits of x.
+func (s *SkipJack32) process(x uint64, n int, width uint, rounds int, encrypt bool) uint64 {
+	var words [4]uint32
+	w := words[:n]
+	mask := uint64(1)<<width - 1
+	for i := range w {
+		w[i] = uint32(x >> (width * uint(n-1-i)) & mask)
+	}
+	s.feistel(w, width, rounds, encrypt)
+	x = 0
+	for i := range w {
+		x = x<<width | uint64(w[i])
+	}
+	return x
+}
+
+// Encrypt returns the encryption of x, which must not exceed MaxUint48.
+func (s *SkipJack48) Encrypt(x uint64) (uint64, error) {
+	if x > MaxUint48 {
+		return 0, fmt.Errorf("error: expected number <= %d, actual: %d", uint64(MaxUint48), x)
+	}
+	return s.s.process(x, 3, wordBits, rounds48, true), nil
+}
+
+// Decrypt returns the decryption of x, which must not exceed MaxUint48.
+func (s *SkipJack48) Decrypt(x uint64) (uint64, error) {
+	if x > MaxUint48 {
+		return 0, fmt.Errorf("error: expected number <= %d, actual: %d", uint64(MaxUint48), x)
+	}
+	return s.s.process(x, 3, wordBits, rounds48, false), nil
+}
+
+// EncryptInt64 returns the encryption of x, which must be between 0 and
+// MaxUint48. The result is in the same range, so positive ids stay
+// positive.
+func (s *SkipJack48) EncryptInt64(x int64) (int64, error) {
+	if x < 0 {
+		return 0, fmt.Errorf("error: expected number >= 0, actual: %d", x)
+	}
+	y, err := s.Encrypt(uint64(x))
+	return int64(y), err
+}
+
+// DecryptInt64 returns the decryption of x, which must be between 0 and
+// MaxUint48.
+func (s *SkipJack48) DecryptInt64(x int64) (int64, error) {
+	if x < 0 {
+		return 0, fmt.Errorf("error: expected number >= 0, actual: %d", x)
+	}
+	y, err := s.Decrypt(uint64(x))
+	return int64(y), err
+}
+
+// Encrypt returns the encryption of x.
+func (s *SkipJack64) Encrypt(x uint64) uint64 {
+	return s.s.process(x, 4, wordBits, rounds64, true)
+}
+
+// Decrypt returns the decryption of x.
+func (s *SkipJack64) Decrypt(x uint64) uint64 {
+	return s.s.process(x, 4, wordBits, rounds64, false)
+}
+
+// EncryptInt64 returns the encryption of x as a two's complement
+// permutation of int64: half of the positive ids encrypt to negative
+// numbers. Use SkipJack48 where ids must stay positive.
+func (s *SkipJack64) EncryptInt64(x int64) int64 {
+	return int64(s.Encrypt(uint64(x)))
+}
+
+// DecryptInt64 returns the decryption of x, see EncryptInt64.
+func (s *SkipJack64) DecryptInt64(x int64) int64 {
+	return int64(s.Decrypt(uint64(x)))
+}
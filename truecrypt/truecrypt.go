@@ -0,0 +1,172 @@
+package truecrypt
+
+//  Decryption of TrueCrypt and VeraCrypt volume headers.
+//
+//  The header is the first 512 bytes of a volume: a 64-byte salt
+//  followed by 448 bytes encrypted in XTS mode with data unit 0 under
+//  a key derived from the password with PBKDF2:
+//    https://www.veracrypt.fr/en/VeraCrypt%20Volume%20Format%20Specification.html
+//    https://www.veracrypt.fr/en/Header%20Key%20Derivation.html
+//
+//  Only volumes encrypted with AES are supported; cascades and the
+//  other ciphers of TrueCrypt and VeraCrypt are not.
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+
+	"github.com/y3sh/go-legacy-crypto/kdf"
+	"github.com/y3sh/go-legacy-crypto/ripemd160"
+	"github.com/y3sh/go-legacy-crypto/whirlpool"
+)
+
+// HeaderSize is the size of a volume header in bytes.
+const HeaderSize = 512
+
+const (
+	saltSize      = 64
+	headerKeySize = 64 // two AES-256 keys for XTS
+	keyAreaOffset = 256
+	keyAreaSize   = 256
+)
+
+var (
+	ErrHeaderSize        = errors.New("truecrypt: header must be 512 bytes")
+	ErrIncorrectPassword = errors.New("truecrypt: incorrect password or unsupported volume")
+)
+
+// Header is a decrypted volume header.
+type Header struct {
+	Magic              string // "TRUE" or "VERA"
+	Version            uint16 // header format version
+	MinProgramVersion  uint16 // minimum program version to open the volume
+	HiddenVolumeSize   uint64 // zero unless this is a hidden volume header
+	VolumeSize         uint64
+	EncryptedAreaStart uint64 // byte offset of the master key scope
+	EncryptedAreaSize  uint64
+	Flags              uint32
+	SectorSize         uint32
+	MasterKeys         [keyAreaSize]byte // the first 64 bytes are the AES-XTS master key
+
+	// The key derivation that opened the header.
+	PRF        string
+	Iterations int
+}
+
+// Options select the key derivations tried by DecryptHeader.
+type Options struct {
+	// PIM is the VeraCrypt Personal Iterations Multiplier. When it is
+	// set only the VeraCrypt iteration counts it selects are tried.
+	PIM int
+	// System selects the iteration counts of system encryption.
+	System bool
+}
+
+// A KDF is one of the PBKDF2 configurations tried by DecryptHeader.
+type KDF struct {
+	PRF        string
+	Hash       func() hash.Hash
+	Iterations int
+}
+
+// KDFs returns the key derivations tried by DecryptHeader for opts,
+// cheapest first: TrueCrypt, then VeraCrypt.
+func KDFs(opts *Options) []KDF {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.PIM > 0 {
+		// system encryption only lowers the count of SHA-256 and
+		// RIPEMD-160, the PRFs of the legacy boot loader.
+		iter := 15000 + 1000*opts.PIM
+		bootIter := iter
+		if opts.System {
+			bootIter = 2048 * opts.PIM
+		}
+		return []KDF{
+			{"HMAC-SHA-512", sha512.New, iter},
+			{"HMAC-Whirlpool", whirlpool.New, iter},
+			{"HMAC-SHA-256", sha256.New, bootIter},
+			{"HMAC-RIPEMD-160", ripemd160.New, bootIter},
+		}
+	}
+	if opts.System {
+		return []KDF{
+			{"HMAC-RIPEMD-160", ripemd160.New, 1000},
+			{"HMAC-SHA-256", sha256.New, 200000},
+			{"HMAC-RIPEMD-160", ripemd160.New, 327661},
+			{"HMAC-SHA-512", sha512.New, 500000},
+			{"HMAC-Whirlpool", whirlpool.New, 500000},
+		}
+	}
+	return []KDF{
+		{"HMAC-SHA-512", sha512.New, 1000},
+		{"HMAC-Whirlpool", whirlpool.New, 1000},
+		{"HMAC-RIPEMD-160", ripemd160.New, 2000},
+		{"HMAC-SHA-512", sha512.New, 500000},
+		{"HMAC-Whirlpool", whirlpool.New, 500000},
+		{"HMAC-SHA-256", sha256.New, 500000},
+		{"HMAC-RIPEMD-160", ripemd160.New, 655331},
+	}
+}
+
+// DecryptHeader tries every key derivation of KDFs(opts) on the
+// password and the first 512 bytes of a volume, and returns the first
+// header whose magic and checksums verify. A nil opts tries the
+// default iteration counts of TrueCrypt and VeraCrypt.
+func DecryptHeader(password, header []byte, opts *Options) (*Header, error) {
+	if len(header) != HeaderSize {
+		return nil, ErrHeaderSize
+	}
+	salt := header[:saltSize]
+	for _, k := range KDFs(opts) {
+		key := kdf.Key(k.Hash, password, salt, k.Iterations, headerKeySize)
+		if h, ok := decryptHeader(key, header); ok {
+			h.PRF = k.PRF
+			h.Iterations = k.Iterations
+			return h, nil
+		}
+	}
+	return nil, ErrIncorrectPassword
+}
+
+// decryptHeader decrypts the header with a derived header key and
+// parses it if the magic and both checksums verify.
+func decryptHeader(key, header []byte) (*Header, bool) {
+	x, err := newXTS(key)
+	if err != nil {
+		return nil, false
+	}
+	var plain [HeaderSize]byte
+	copy(plain[:saltSize], header)
+	x.decrypt(plain[saltSize:], header[saltSize:], 0)
+
+	magic := string(plain[64:68])
+	if magic != "TRUE" && magic != "VERA" {
+		return nil, false
+	}
+	be := binary.BigEndian
+	if crc32.ChecksumIEEE(plain[keyAreaOffset:]) != be.Uint32(plain[72:]) ||
+		crc32.ChecksumIEEE(plain[64:252]) != be.Uint32(plain[252:]) {
+		return nil, false
+	}
+	h := &Header{
+		Magic:              magic,
+		Version:            be.Uint16(plain[68:]),
+		MinProgramVersion:  be.Uint16(plain[70:]),
+		HiddenVolumeSize:   be.Uint64(plain[92:]),
+		VolumeSize:         be.Uint64(plain[100:]),
+		EncryptedAreaStart: be.Uint64(plain[108:]),
+		EncryptedAreaSize:  be.Uint64(plain[116:]),
+		Flags:              be.Uint32(plain[124:]),
+		SectorSize:         be.Uint32(plain[128:]),
+	}
+	if h.Version < 5 || h.SectorSize == 0 {
+		h.SectorSize = 512
+	}
+	copy(h.MasterKeys[:], plain[keyAreaOffset:])
+	return h, true
+}
@@ -0,0 +1,187 @@
+package truecrypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/kdf"
+	"github.com/y3sh/go-legacy-crypto/ripemd160"
+	"github.com/y3sh/go-legacy-crypto/whirlpool"
+)
+
+func TestXTS(t *testing.T) {
+	// IEEE 1619-2007 XTS-AES-128 vectors 1 and 2
+	assertXTS(t, "00000000000000000000000000000000"+"00000000000000000000000000000000", 0,
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"917cf69ebd68b2ec9b9fe9a3eadda692cd43d2f59598ed858c02c2652fbf922e")
+	assertXTS(t, "11111111111111111111111111111111"+"22222222222222222222222222222222", 0x3333333333,
+		"4444444444444444444444444444444444444444444444444444444444444444",
+		"c454185e6a16936e39334038acef838bfb186fff7480adc4289382ecd6d394f0")
+}
+
+func TestDecryptTrueCryptHeader(t *testing.T) {
+	expected := syntheticHeader("TRUE", 5)
+	volume := encryptHeader(t, expected, []byte("password"), ripemd160.New, 2000)
+
+	h, err := DecryptHeader([]byte("password"), volume, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.PRF != "HMAC-RIPEMD-160" || h.Iterations != 2000 {
+		t.Errorf("Expected HMAC-RIPEMD-160 with 2000 iterations, got %v with %d", h.PRF, h.Iterations)
+	}
+	assertHeader(t, h, expected)
+}
+
+func TestDecryptVeraCryptHeaderWithPIM(t *testing.T) {
+	expected := syntheticHeader("VERA", 5)
+	volume := encryptHeader(t, expected, []byte("correct horse"), whirlpool.New, 15000+1000*3)
+
+	h, err := DecryptHeader([]byte("correct horse"), volume, &Options{PIM: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.PRF != "HMAC-Whirlpool" || h.Iterations != 18000 {
+		t.Errorf("Expected HMAC-Whirlpool with 18000 iterations, got %v with %d", h.PRF, h.Iterations)
+	}
+	assertHeader(t, h, expected)
+
+	if _, err := DecryptHeader([]byte("wrong horse"), volume, &Options{PIM: 3}); err != ErrIncorrectPassword {
+		t.Errorf("Expected ErrIncorrectPassword, got %v", err)
+	}
+}
+
+func TestDecryptSystemHeaderWithPIM(t *testing.T) {
+	expected := syntheticHeader("VERA", 5)
+	expected.Flags = 1 // system encryption
+	vectors := []struct {
+		prf        string
+		hash       func() hash.Hash
+		iterations int
+	}{
+		{"HMAC-SHA-512", sha512.New, 15000 + 1000*2},
+		{"HMAC-Whirlpool", whirlpool.New, 15000 + 1000*2},
+		{"HMAC-SHA-256", sha256.New, 2048 * 2},
+		{"HMAC-RIPEMD-160", ripemd160.New, 2048 * 2},
+	}
+	for _, v := range vectors {
+		volume := encryptHeader(t, expected, []byte("system"), v.hash, v.iterations)
+		h, err := DecryptHeader([]byte("system"), volume, &Options{PIM: 2, System: true})
+		if err != nil {
+			t.Errorf("%s: Expected no error, got %v", v.prf, err)
+			continue
+		}
+		if h.PRF != v.prf || h.Iterations != v.iterations {
+			t.Errorf("Expected %v with %d iterations, got %v with %d", v.prf, v.iterations, h.PRF, h.Iterations)
+		}
+		assertHeader(t, h, expected)
+	}
+}
+
+func TestDecryptHeaderChecksums(t *testing.T) {
+	volume := encryptHeader(t, syntheticHeader("VERA", 5), []byte("password"), whirlpool.New, 16000)
+	if _, err := DecryptHeader([]byte("password"), volume, &Options{PIM: 1}); err != nil {
+		t.Fatal(err)
+	}
+	volume[300] ^= 1 // garbles one block of the key area
+
+	if _, err := DecryptHeader([]byte("password"), volume[:HeaderSize-1], nil); err != ErrHeaderSize {
+		t.Errorf("Expected ErrHeaderSize, got %v", err)
+	}
+	if _, err := DecryptHeader([]byte("password"), volume, &Options{PIM: 1}); err != ErrIncorrectPassword {
+		t.Errorf("Expected ErrIncorrectPassword, got %v", err)
+	}
+}
+
+func TestDecryptLegacyHeaderSectorSize(t *testing.T) {
+	expected := syntheticHeader("TRUE", 4)
+	expected.SectorSize = 512
+	volume := encryptHeader(t, expected, []byte("password"), whirlpool.New, 1000)
+
+	h, err := DecryptHeader([]byte("password"), volume, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHeader(t, h, expected)
+}
+
+func syntheticHeader(magic string, version uint16) *Header {
+	h := &Header{
+		Magic:              magic,
+		Version:            version,
+		MinProgramVersion:  0x0700,
+		VolumeSize:         10 << 20,
+		EncryptedAreaStart: 128 << 10,
+		EncryptedAreaSize:  10 << 20,
+		SectorSize:         4096,
+	}
+	for i := range h.MasterKeys {
+		h.MasterKeys[i] = byte(31 * i)
+	}
+	return h
+}
+
+// encryptHeader builds the on-disk header of h, with valid checksums,
+// the way TrueCrypt and VeraCrypt write it.
+func encryptHeader(t *testing.T, h *Header, password []byte, prf func() hash.Hash, iter int) []byte {
+	var plain [HeaderSize]byte
+	for i := 0; i < saltSize; i++ {
+		plain[i] = byte(i*7 + 3)
+	}
+	be := binary.BigEndian
+	copy(plain[64:], h.Magic)
+	be.PutUint16(plain[68:], h.Version)
+	be.PutUint16(plain[70:], h.MinProgramVersion)
+	be.PutUint64(plain[92:], h.HiddenVolumeSize)
+	be.PutUint64(plain[100:], h.VolumeSize)
+	be.PutUint64(plain[108:], h.EncryptedAreaStart)
+	be.PutUint64(plain[116:], h.EncryptedAreaSize)
+	be.PutUint32(plain[124:], h.Flags)
+	if h.Version >= 5 {
+		be.PutUint32(plain[128:], h.SectorSize)
+	}
+	copy(plain[keyAreaOffset:], h.MasterKeys[:])
+	be.PutUint32(plain[72:], crc32.ChecksumIEEE(plain[keyAreaOffset:]))
+	be.PutUint32(plain[252:], crc32.ChecksumIEEE(plain[64:252]))
+
+	x, err := newXTS(kdf.Key(prf, password, plain[:saltSize], iter, headerKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	volume := make([]byte, HeaderSize)
+	copy(volume, plain[:saltSize])
+	x.encrypt(volume[saltSize:], plain[saltSize:], 0)
+	return volume
+}
+
+func assertHeader(t *testing.T, actual, expected *Header) {
+	a, e := *actual, *expected
+	a.PRF, a.Iterations = "", 0
+	if a != e {
+		t.Errorf("Expected %+v, got %+v", e, a)
+	}
+}
+
+func assertXTS(t *testing.T, key string, unit uint64, plaintext, ciphertext string) {
+	k, _ := hex.DecodeString(key)
+	p, _ := hex.DecodeString(plaintext)
+	x, err := newXTS(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := make([]byte, len(p))
+	x.encrypt(c, p, unit)
+	if actual := hex.EncodeToString(c); actual != ciphertext {
+		t.Errorf("Expected %v, got %v", ciphertext, actual)
+	}
+	x.decrypt(c, c, unit)
+	if !bytes.Equal(c, p) {
+		t.Errorf("Expected decryption to restore %x, got %x", p, c)
+	}
+}
@@ -0,0 +1,71 @@
+package truecrypt
+
+// XTS-AES as specified by IEEE 1619-2007, restricted to data units
+// that are a whole number of AES blocks, which is all TrueCrypt and
+// VeraCrypt use.
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+type xts struct {
+	k1, k2 cipher.Block // data and tweak keys
+}
+
+// newXTS returns an XTS-AES instance for a key of two AES keys of
+// equal length.
+func newXTS(key []byte) (*xts, error) {
+	k1, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	k2, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return &xts{k1: k1, k2: k2}, nil
+}
+
+func (x *xts) encrypt(dst, src []byte, unit uint64) {
+	x.crypt(dst, src, unit, x.k1.Encrypt)
+}
+
+func (x *xts) decrypt(dst, src []byte, unit uint64) {
+	x.crypt(dst, src, unit, x.k1.Decrypt)
+}
+
+func (x *xts) crypt(dst, src []byte, unit uint64, block func(dst, src []byte)) {
+	if len(src)%aes.BlockSize != 0 {
+		panic("truecrypt: XTS data unit is not a multiple of the block size")
+	}
+	var tweak, buf [aes.BlockSize]byte
+	binary.LittleEndian.PutUint64(tweak[:8], unit)
+	x.k2.Encrypt(tweak[:], tweak[:])
+	for len(src) > 0 {
+		for i := range buf {
+			buf[i] = src[i] ^ tweak[i]
+		}
+		block(buf[:], buf[:])
+		for i := range buf {
+			dst[i] = buf[i] ^ tweak[i]
+		}
+		mulAlpha(&tweak)
+		src = src[aes.BlockSize:]
+		dst = dst[aes.BlockSize:]
+	}
+}
+
+// mulAlpha multiplies the tweak by the primitive element of GF(2^128),
+// with the little-endian byte order of IEEE 1619.
+func mulAlpha(t *[aes.BlockSize]byte) {
+	var carry byte
+	for i := range t {
+		next := t[i] >> 7
+		t[i] = t[i]<<1 | carry
+		carry = next
+	}
+	if carry != 0 {
+		t[0] ^= 0x87
+	}
+}
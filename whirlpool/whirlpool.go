@@ -9,63 +9,254 @@ package whirlpool
 //    https://github.com/torvalds/linux/blob/master/crypto/wp512.c
 import (
 	"fmt"
+	"hash"
 )
 
+// HashOfBytes returns the Whirlpool checksum of salt followed by ar.
+//
+// Deprecated: the boundary between salt and data is ambiguous. Use
+// NewHMAC or MAC for keyed digests and NewSalted for salted ones.
 func HashOfBytes(ar []byte, salt []byte) []byte {
-	var input []byte
-	input = append(input, salt[:]...)
-	input = append(input, ar...)
-	var hash = Sum512(input)
-	return hash[:]
+	selfTest.MustCheck()
+	var d Hash
+	d.Write(salt)
+	d.Write(ar)
+	return d.Sum(nil)
 }
 
+// HashOfString returns the Whirlpool checksum of salt followed by s.
+//
+// Deprecated: the boundary between salt and data is ambiguous. Use
+// NewHMAC or MAC for keyed digests and NewSalted for salted ones.
 func HashOfString(s string, salt []byte) []byte {
-	var input []byte
-	input = append(input, salt[:]...)
-	input = append(input, []byte(s)...)
-	var hash = Sum512(input)
-	return hash[:]
+	selfTest.MustCheck()
+	var d Hash
+	d.Write(salt)
+	d.WriteString(s)
+	return d.Sum(nil)
 }
 
-func Sum512(data []byte) [cDigestBytes]byte {
-	var hash = New()
-	appendBytes(data, uint64(8*len(data)), &hash)
-	var digest [cDigestBytes]byte
-	finalize(&hash, digest[:])
+// The size of a Whirlpool checksum in bytes.
+const Size = cDigestBytes
+
+// The sizes in bytes of the truncated wp384 and wp256 checksums of the
+// Linux kernel, the leading bytes of the Whirlpool checksum.
+const (
+	Size384 = 48
+	Size256 = 32
+)
+
+// The block size of the hash algorithm in bytes.
+const BlockSize = cWBlockBytes
+
+// Sum512 returns the Whirlpool checksum of the data.
+func Sum512(data []byte) [Size]byte {
+	selfTest.MustCheck()
+	var d Hash
+	d.Write(data)
+	var digest [Size]byte
+	finalize(&d, digest[:])
 	return digest
 }
 
+// Sum384 returns the wp384 checksum of the data.
+func Sum384(data []byte) [Size384]byte {
+	digest := Sum512(data)
+	var truncated [Size384]byte
+	copy(truncated[:], digest[:])
+	return truncated
+}
+
+// Sum256 returns the wp256 checksum of the data.
+func Sum256(data []byte) [Size256]byte {
+	digest := Sum512(data)
+	var truncated [Size256]byte
+	copy(truncated[:], digest[:])
+	return truncated
+}
+
+// SumBits returns the Whirlpool checksum of the first nbits bits of data,
+// taken most significant bit first.
+func SumBits(data []byte, nbits uint64) [Size]byte {
+	selfTest.MustCheck()
+	var d Hash
+	d.WriteBits(data, nbits)
+	var digest [Size]byte
+	finalize(&d, digest[:])
+	return digest
+}
+
+// Hash represents the partial evaluation of a Whirlpool checksum.
+// The zero value computes Whirlpool and accepts bit-granular input
+// through WriteBits.
 type Hash struct {
-	bitLength  [cLengthBytes]byte
-	buffer     [cWBlockBytes]byte
-	bufferBits int
-	bufferPos  int
+	bitLength  [cLengthBytes]byte // number of hashed bits
+	buffer     [cWBlockBytes]byte // buffer of data to hash
+	bufferBits int                // current number of bits on the buffer
+	bufferPos  int                // current (possibly incomplete) byte slot on the buffer
 	hash       [cDigestBytes / 8]uint64
+	t          *tables // lookup tables of the variant, nil for Whirlpool
+	tracer     Tracer
+	size       int        // length of the truncated checksum, 0 for Size
+	compact    bool       // compress with a single lookup table
+	bs         *bitsliced // constant-time compression, nil for the tables
 }
 
-func New() Hash {
-	var ret Hash
-	if cTraceIntermediateValues {
-		fmt.Printf("Initial hash value:" + LB)
-		for i := 0; i < cDigestBytes/8; i++ {
-			fmt.Printf("    %02X %02X %02X %02X %02X %02X %02X %02X"+LB,
-				byte(ret.hash[i]>>56),
-				byte(ret.hash[i]>>48),
-				byte(ret.hash[i]>>40),
-				byte(ret.hash[i]>>32),
-				byte(ret.hash[i]>>24),
-				byte(ret.hash[i]>>16),
-				byte(ret.hash[i]>>8),
-				byte(ret.hash[i]))
-		}
-		fmt.Printf(LB)
+// An Option configures a Hash returned by NewVariant.
+type Option func(*Hash)
+
+// WithTracer reports the intermediate values of every compression
+// function call to tr. It cannot be combined with WithConstantTime.
+func WithTracer(tr Tracer) Option {
+	return func(ob *Hash) {
+		ob.tracer = tr
+	}
+}
+
+// WithCompactTables makes the compression function use a single 2 KiB
+// lookup table and rotations instead of eight tables. This is slower,
+// but uses less cache, which may pay off when hashing short messages
+// among other work.
+func WithCompactTables() Option {
+	return func(ob *Hash) {
+		ob.compact = true
+	}
+}
+
+// WithConstantTime makes the compression function run in time
+// independent of the hashed data, with a bitsliced S-box instead of
+// lookup tables indexed by secret bytes. It is an order of magnitude
+// slower, and meant for keyed uses such as HMAC and key derivation
+// where cache timing may leak the key. Tracing needs the table-based
+// compression function, so NewVariant panics if it is combined with
+// WithTracer.
+func WithConstantTime() Option {
+	return func(ob *Hash) {
+		ob.bs = ob.variant().bitsliced()
+	}
+}
+
+// New returns a new hash.Hash computing the Whirlpool checksum.
+func New() hash.Hash {
+	return NewVariant(Whirlpool)
+}
+
+// New384 returns a new hash.Hash computing the wp384 checksum, the
+// Whirlpool checksum truncated to 48 bytes.
+func New384() hash.Hash {
+	selfTest.MustCheck()
+	return &Hash{size: Size384}
+}
+
+// New256 returns a new hash.Hash computing the wp256 checksum, the
+// Whirlpool checksum truncated to 32 bytes.
+func New256() hash.Hash {
+	selfTest.MustCheck()
+	return &Hash{size: Size256}
+}
+
+// NewVariant returns a new hash.Hash computing the checksum of the
+// given Whirlpool revision. Whirlpool0 and WhirlpoolT are only meant
+// for verifying digests produced before the 2003 revision.
+func NewVariant(v Variant, opts ...Option) hash.Hash {
+	selfTest.MustCheck()
+	ret := &Hash{t: v.tables()}
+	for _, opt := range opts {
+		opt(ret)
+	}
+	if ret.tracer != nil && ret.bs != nil {
+		panic("whirlpool: WithTracer cannot be combined with WithConstantTime")
+	}
+	if ret.tracer != nil {
+		ret.tracer.InitialState(Matrix(ret.hash))
 	}
 	return ret
 }
 
+func (ob *Hash) Reset() {
+	*ob = Hash{t: ob.t, tracer: ob.tracer, size: ob.size, compact: ob.compact, bs: ob.bs}
+	if ob.tracer != nil {
+		ob.tracer.InitialState(Matrix(ob.hash))
+	}
+}
+
+func (ob *Hash) Size() int {
+	if ob.size != 0 {
+		return ob.size
+	}
+	return Size
+}
+
+func (ob *Hash) BlockSize() int { return BlockSize }
+
 func (ob *Hash) Write(data []byte) (n int, err error) {
-	appendBytes(data, uint64(8*len(data)), ob)
-	return len(data), nil
+	n = len(data)
+	if ob.bufferBits&7 != 0 {
+		// a partial byte is pending, shift everything into place.
+		appendBytes(data, uint64(8*n), ob)
+		return n, nil
+	}
+	addBitLength(&ob.bitLength, uint64(8*n))
+	if ob.bufferPos > 0 {
+		k := copy(ob.buffer[ob.bufferPos:], data)
+		ob.bufferPos += k
+		data = data[k:]
+		if ob.bufferPos == cWBlockBytes {
+			ob.compress(ob.buffer[:])
+			ob.bufferPos = 0
+		}
+	}
+	if len(data) >= cWBlockBytes {
+		// hash whole blocks straight from data.
+		k := len(data) &^ (cWBlockBytes - 1)
+		ob.compress(data[:k])
+		data = data[k:]
+	}
+	if len(data) > 0 {
+		ob.bufferPos += copy(ob.buffer[ob.bufferPos:], data)
+	}
+	// appendBytes and finalize expect the current byte slot to be clear.
+	ob.buffer[ob.bufferPos] = 0
+	ob.bufferBits = 8 * ob.bufferPos
+	return n, nil
+}
+
+// WriteString adds the bytes of s to the running hash without first
+// copying all of s into a byte slice.
+func (ob *Hash) WriteString(s string) (n int, err error) {
+	var chunk [cWBlockBytes]byte
+	n = len(s)
+	for len(s) > 0 {
+		k := copy(chunk[:], s)
+		ob.Write(chunk[:k])
+		s = s[k:]
+	}
+	return n, nil
+}
+
+func (ob0 *Hash) Sum(in []byte) []byte {
+	// Make a copy of ob0 so that caller can keep writing and summing.
+	ob := *ob0
+	var digest [Size]byte
+	finalize(&ob, digest[:])
+	return append(in, digest[:ob.Size()]...)
+}
+
+// WriteBits adds the first nbits bits of data, most significant bit
+// first, to the running hash. Unlike Write, nbits need not be a
+// multiple of 8, and bit-granular and byte-granular writes may be
+// freely interleaved. WriteBits panics if nbits exceeds 8*len(data).
+func (ob *Hash) WriteBits(data []byte, nbits uint64) {
+	if nbits > 8*uint64(len(data)) {
+		panic("whirlpool: WriteBits length exceeds data")
+	}
+	full := nbits / 8
+	appendBytes(data[:full], 8*full, ob)
+	if rem := nbits % 8; rem != 0 {
+		// appendBytes expects a partial byte to be right-justified.
+		last := [1]byte{data[full] >> (8 - rem)}
+		appendBytes(last[:], rem, ob)
+	}
 }
 
 func appendBytes(source []byte, sourceBits uint64, ob *Hash) {
@@ -77,16 +268,7 @@ func appendBytes(source []byte, sourceBits uint64, ob *Hash) {
 	var bitLength = &ob.bitLength
 	var bufferBits = ob.bufferBits
 	var bufferPos = ob.bufferPos
-	{
-		var carry = uint32(0)
-		var val = uint64(sourceBits)
-		for i := 31; i >= 0 && (carry != 0 || val != 0); i-- {
-			carry += uint32(bitLength[i]) + (uint32(val) & 0xff)
-			bitLength[i] = byte(carry)
-			carry >>= 8
-			val >>= 8
-		}
-	}
+	addBitLength(bitLength, sourceBits)
 	for sourceBits > 8 {
 		b = uint32((source[sourcePos]<<uint32(sourceGap))&0xff) |
 			uint32((source[sourcePos+1]&0xff)>>uint32(8-sourceGap))
@@ -94,7 +276,7 @@ func appendBytes(source []byte, sourceBits uint64, ob *Hash) {
 		bufferPos++
 		bufferBits += 8 - bufferRem
 		if bufferBits == cDigestBits {
-			processBuffer(ob)
+			ob.compress(buffer)
 			bufferBits = 0
 			bufferPos = 0
 		}
@@ -124,7 +306,7 @@ func appendBytes(source []byte, sourceBits uint64, ob *Hash) {
 		// furthermore, all data (if any is left) is in source[sourcePos].
 		if bufferBits == cDigestBits {
 			// process data block:
-			processBuffer(ob)
+			ob.compress(buffer)
 			// reset buffer:
 			bufferBits = 0
 			bufferPos = 0
@@ -136,6 +318,17 @@ func appendBytes(source []byte, sourceBits uint64, ob *Hash) {
 	ob.bufferPos = bufferPos
 }
 
+// addBitLength adds n to the 256-bit big-endian counter bitLength.
+func addBitLength(bitLength *[cLengthBytes]byte, n uint64) {
+	var carry = uint32(0)
+	for i := cLengthBytes - 1; i >= 0 && (carry != 0 || n != 0); i-- {
+		carry += uint32(bitLength[i]) + (uint32(n) & 0xff)
+		bitLength[i] = byte(carry)
+		carry >>= 8
+		n >>= 8
+	}
+}
+
 func finalize(ob *Hash, result []byte) {
 	var buffer = ob.buffer[:]
 	var bufferBits = ob.bufferBits
@@ -151,8 +344,8 @@ func finalize(ob *Hash, result []byte) {
 				buffer[i] = 0
 			}
 		}
-		processBuffer(ob) // process data block
-		bufferPos = 0     // reset buffer
+		ob.compress(buffer) // process data block
+		bufferPos = 0       // reset buffer
 	}
 	if bufferPos < cWBlockBytes-cLengthBytes {
 		for i := bufferPos; i < cWBlockBytes-cLengthBytes; i++ {
@@ -165,7 +358,7 @@ func finalize(ob *Hash, result []byte) {
 	copy(buffer[cWBlockBytes-cLengthBytes:], bitLength[:cLengthBytes])
 	//
 	// process data block
-	processBuffer(ob)
+	ob.compress(buffer)
 	//
 	// return the completed message digest:
 	for i, b := 0, 0; i < cDigestBytes/8; i++ {
@@ -183,282 +376,6 @@ func finalize(ob *Hash, result []byte) {
 	ob.bufferPos = bufferPos
 }
 
-func processBuffer(ob *Hash) {
-	var K [8]uint64     // the round key
-	var block [8]uint64 // mu(buffer)
-	var state [8]uint64 // the cipher state
-	var L [8]uint64
-	var buffer = ob.buffer[:]
-	if cTraceIntermediateValues {
-		fmt.Printf("The 8x8 matrix Z' derived from the" +
-			" data-string is as follows." + LB)
-		for i, b := 0, 0; i < cWBlockBytes/8; i++ {
-			fmt.Printf("    %02X %02X %02X %02X %02X %02X %02X %02X"+LB,
-				buffer[b+0], buffer[b+1], buffer[b+2], buffer[b+3],
-				buffer[b+4], buffer[b+5], buffer[b+6], buffer[b+7])
-			b += 8
-		}
-		fmt.Printf(LB)
-		buffer = ob.buffer[:]
-	}
-	// map the buffer to a block:
-	for i, b := 0, 0; i < 8; i++ {
-		block[i] = ((uint64(buffer[b+0])) << 56) ^
-			((uint64(buffer[b+1]) & 0xff) << 48) ^
-			((uint64(buffer[b+2]) & 0xff) << 40) ^
-			((uint64(buffer[b+3]) & 0xff) << 32) ^
-			((uint64(buffer[b+4]) & 0xff) << 24) ^
-			((uint64(buffer[b+5]) & 0xff) << 16) ^
-			((uint64(buffer[b+6]) & 0xff) << 8) ^
-			(uint64(buffer[b+7]) & 0xff)
-		b += 8
-	}
-	// compute and apply K^0 to the cipher state:
-	for i := 0; i < 8; i++ {
-		K[i] = ob.hash[i]
-		state[i] = block[i] ^ K[i]
-	}
-	if cTraceIntermediateValues {
-		fmt.Printf("The K_0 matrix (from the initialization value IV)" +
-			" and X'' matrix are as follows." + LB)
-		for i := 0; i < cDigestBytes/8; i++ {
-			fmt.Printf(
-				"    %02X %02X %02X %02X %02X %02X %02X %02X    "+
-					"    %02X %02X %02X %02X %02X %02X %02X %02X"+LB,
-				byte(K[i]>>56),
-				byte(K[i]>>48),
-				byte(K[i]>>40),
-				byte(K[i]>>32),
-				byte(K[i]>>24),
-				byte(K[i]>>16),
-				byte(K[i]>>8),
-				byte(K[i]),
-				byte(state[i]>>56),
-				byte(state[i]>>48),
-				byte(state[i]>>40),
-				byte(state[i]>>32),
-				byte(state[i]>>24),
-				byte(state[i]>>16),
-				byte(state[i]>>8),
-				byte(state[i]),
-			)
-		}
-		fmt.Printf(LB +
-			"The following are (hexadecimal representations of) the" +
-			" successive values of the variables" +
-			" K_i for i = 1 to 10 and W'." + LB + LB)
-	}
-	// iterate over all rounds:
-	for r := 1; r <= cRounds; r++ {
-		// compute K^r from K^{r-1}:
-		L[0] = cC0[int(K[0]>>56)] ^
-			cC1[int(K[7]>>48)&0xff] ^
-			cC2[int(K[6]>>40)&0xff] ^
-			cC3[int(K[5]>>32)&0xff] ^
-			cC4[int(K[4]>>24)&0xff] ^
-			cC5[int(K[3]>>16)&0xff] ^
-			cC6[int(K[2]>>8)&0xff] ^
-			cC7[int(K[1])&0xff] ^
-			rc[r]
-		L[1] = cC0[int(K[1]>>56)] ^
-			cC1[int(K[0]>>48)&0xff] ^
-			cC2[int(K[7]>>40)&0xff] ^
-			cC3[int(K[6]>>32)&0xff] ^
-			cC4[int(K[5]>>24)&0xff] ^
-			cC5[int(K[4]>>16)&0xff] ^
-			cC6[int(K[3]>>8)&0xff] ^
-			cC7[int(K[2])&0xff]
-		L[2] = cC0[int(K[2]>>56)] ^
-			cC1[int(K[1]>>48)&0xff] ^
-			cC2[int(K[0]>>40)&0xff] ^
-			cC3[int(K[7]>>32)&0xff] ^
-			cC4[int(K[6]>>24)&0xff] ^
-			cC5[int(K[5]>>16)&0xff] ^
-			cC6[int(K[4]>>8)&0xff] ^
-			cC7[int(K[3])&0xff]
-		L[3] = cC0[int(K[3]>>56)] ^
-			cC1[int(K[2]>>48)&0xff] ^
-			cC2[int(K[1]>>40)&0xff] ^
-			cC3[int(K[0]>>32)&0xff] ^
-			cC4[int(K[7]>>24)&0xff] ^
-			cC5[int(K[6]>>16)&0xff] ^
-			cC6[int(K[5]>>8)&0xff] ^
-			cC7[int(K[4])&0xff]
-		L[4] = cC0[int(K[4]>>56)] ^
-			cC1[int(K[3]>>48)&0xff] ^
-			cC2[int(K[2]>>40)&0xff] ^
-			cC3[int(K[1]>>32)&0xff] ^
-			cC4[int(K[0]>>24)&0xff] ^
-			cC5[int(K[7]>>16)&0xff] ^
-			cC6[int(K[6]>>8)&0xff] ^
-			cC7[int(K[5])&0xff]
-		L[5] = cC0[int(K[5]>>56)] ^
-			cC1[int(K[4]>>48)&0xff] ^
-			cC2[int(K[3]>>40)&0xff] ^
-			cC3[int(K[2]>>32)&0xff] ^
-			cC4[int(K[1]>>24)&0xff] ^
-			cC5[int(K[0]>>16)&0xff] ^
-			cC6[int(K[7]>>8)&0xff] ^
-			cC7[int(K[6])&0xff]
-		L[6] = cC0[int(K[6]>>56)] ^
-			cC1[int(K[5]>>48)&0xff] ^
-			cC2[int(K[4]>>40)&0xff] ^
-			cC3[int(K[3]>>32)&0xff] ^
-			cC4[int(K[2]>>24)&0xff] ^
-			cC5[int(K[1]>>16)&0xff] ^
-			cC6[int(K[0]>>8)&0xff] ^
-			cC7[int(K[7])&0xff]
-		L[7] = cC0[int(K[7]>>56)] ^
-			cC1[int(K[6]>>48)&0xff] ^
-			cC2[int(K[5]>>40)&0xff] ^
-			cC3[int(K[4]>>32)&0xff] ^
-			cC4[int(K[3]>>24)&0xff] ^
-			cC5[int(K[2]>>16)&0xff] ^
-			cC6[int(K[1]>>8)&0xff] ^
-			cC7[int(K[0])&0xff]
-		K[0] = L[0]
-		K[1] = L[1]
-		K[2] = L[2]
-		K[3] = L[3]
-		K[4] = L[4]
-		K[5] = L[5]
-		K[6] = L[6]
-		K[7] = L[7]
-		// apply the r-th round transformation:
-		L[0] = cC0[int(state[0]>>56)] ^
-			cC1[int(state[7]>>48)&0xff] ^
-			cC2[int(state[6]>>40)&0xff] ^
-			cC3[int(state[5]>>32)&0xff] ^
-			cC4[int(state[4]>>24)&0xff] ^
-			cC5[int(state[3]>>16)&0xff] ^
-			cC6[int(state[2]>>8)&0xff] ^
-			cC7[int(state[1])&0xff] ^
-			K[0]
-		L[1] = cC0[int(state[1]>>56)] ^
-			cC1[int(state[0]>>48)&0xff] ^
-			cC2[int(state[7]>>40)&0xff] ^
-			cC3[int(state[6]>>32)&0xff] ^
-			cC4[int(state[5]>>24)&0xff] ^
-			cC5[int(state[4]>>16)&0xff] ^
-			cC6[int(state[3]>>8)&0xff] ^
-			cC7[int(state[2])&0xff] ^
-			K[1]
-		L[2] = cC0[int(state[2]>>56)] ^
-			cC1[int(state[1]>>48)&0xff] ^
-			cC2[int(state[0]>>40)&0xff] ^
-			cC3[int(state[7]>>32)&0xff] ^
-			cC4[int(state[6]>>24)&0xff] ^
-			cC5[int(state[5]>>16)&0xff] ^
-			cC6[int(state[4]>>8)&0xff] ^
-			cC7[int(state[3])&0xff] ^
-			K[2]
-		L[3] = cC0[int(state[3]>>56)] ^
-			cC1[int(state[2]>>48)&0xff] ^
-			cC2[int(state[1]>>40)&0xff] ^
-			cC3[int(state[0]>>32)&0xff] ^
-			cC4[int(state[7]>>24)&0xff] ^
-			cC5[int(state[6]>>16)&0xff] ^
-			cC6[int(state[5]>>8)&0xff] ^
-			cC7[int(state[4])&0xff] ^
-			K[3]
-		L[4] = cC0[int(state[4]>>56)] ^
-			cC1[int(state[3]>>48)&0xff] ^
-			cC2[int(state[2]>>40)&0xff] ^
-			cC3[int(state[1]>>32)&0xff] ^
-			cC4[int(state[0]>>24)&0xff] ^
-			cC5[int(state[7]>>16)&0xff] ^
-			cC6[int(state[6]>>8)&0xff] ^
-			cC7[int(state[5])&0xff] ^
-			K[4]
-		L[5] = cC0[int(state[5]>>56)] ^
-			cC1[int(state[4]>>48)&0xff] ^
-			cC2[int(state[3]>>40)&0xff] ^
-			cC3[int(state[2]>>32)&0xff] ^
-			cC4[int(state[1]>>24)&0xff] ^
-			cC5[int(state[0]>>16)&0xff] ^
-			cC6[int(state[7]>>8)&0xff] ^
-			cC7[int(state[6])&0xff] ^
-			K[5]
-		L[6] = cC0[int(state[6]>>56)] ^
-			cC1[int(state[5]>>48)&0xff] ^
-			cC2[int(state[4]>>40)&0xff] ^
-			cC3[int(state[3]>>32)&0xff] ^
-			cC4[int(state[2]>>24)&0xff] ^
-			cC5[int(state[1]>>16)&0xff] ^
-			cC6[int(state[0]>>8)&0xff] ^
-			cC7[int(state[7])&0xff] ^
-			K[6]
-		L[7] = cC0[int(state[7]>>56)] ^
-			cC1[int(state[6]>>48)&0xff] ^
-			cC2[int(state[5]>>40)&0xff] ^
-			cC3[int(state[4]>>32)&0xff] ^
-			cC4[int(state[3]>>24)&0xff] ^
-			cC5[int(state[2]>>16)&0xff] ^
-			cC6[int(state[1]>>8)&0xff] ^
-			cC7[int(state[0])&0xff] ^
-			K[7]
-		state[0] = L[0]
-		state[1] = L[1]
-		state[2] = L[2]
-		state[3] = L[3]
-		state[4] = L[4]
-		state[5] = L[5]
-		state[6] = L[6]
-		state[7] = L[7]
-		if cTraceIntermediateValues {
-			fmt.Printf("i = %d:"+LB, r)
-			for i := 0; i < cDigestBytes/8; i++ {
-				fmt.Printf(
-					"    %02X %02X %02X %02X %02X %02X %02X %02X        "+
-						"%02X %02X %02X %02X %02X %02X %02X %02X"+LB,
-					byte(K[i]>>56),
-					byte(K[i]>>48),
-					byte(K[i]>>40),
-					byte(K[i]>>32),
-					byte(K[i]>>24),
-					byte(K[i]>>16),
-					byte(K[i]>>8),
-					byte(K[i]),
-					byte(state[i]>>56),
-					byte(state[i]>>48),
-					byte(state[i]>>40),
-					byte(state[i]>>32),
-					byte(state[i]>>24),
-					byte(state[i]>>16),
-					byte(state[i]>>8),
-					byte(state[i]),
-				)
-			}
-			fmt.Printf(LB)
-		}
-	}
-	// apply the Miyaguchi-Preneel compression function:
-	ob.hash[0] ^= state[0] ^ block[0]
-	ob.hash[1] ^= state[1] ^ block[1]
-	ob.hash[2] ^= state[2] ^ block[2]
-	ob.hash[3] ^= state[3] ^ block[3]
-	ob.hash[4] ^= state[4] ^ block[4]
-	ob.hash[5] ^= state[5] ^ block[5]
-	ob.hash[6] ^= state[6] ^ block[6]
-	ob.hash[7] ^= state[7] ^ block[7]
-	if cTraceIntermediateValues {
-		fmt.Printf("The value of Y' output from the" +
-			" round-function is as follows." + LB)
-		for i := 0; i < cDigestBytes/8; i++ {
-			fmt.Printf("    %02X %02X %02X %02X %02X %02X %02X %02X"+LB,
-				byte(ob.hash[i]>>56),
-				byte(ob.hash[i]>>48),
-				byte(ob.hash[i]>>40),
-				byte(ob.hash[i]>>32),
-				byte(ob.hash[i]>>24),
-				byte(ob.hash[i]>>16),
-				byte(ob.hash[i]>>8),
-				byte(ob.hash[i]))
-		}
-		fmt.Printf(LB)
-	}
-}
-
 func padHexToString(ar []byte) (ret string) {
 	for i := 0; i < len(ar); i++ {
 		ret += fmt.Sprintf("%02X", ar[i])
@@ -0,0 +1,19 @@
+//go:build amd64 && !purego
+
+package whirlpool
+
+// useAsm selects the assembly compression function at run time. It only
+// needs the baseline amd64 instruction set; clearing it falls back to
+// blockGeneric.
+var useAsm = true
+
+func block(h *[8]uint64, t *tables, p []byte) {
+	if useAsm {
+		blockAMD64(h, t, p)
+		return
+	}
+	blockGeneric(h, t, p)
+}
+
+//go:noescape
+func blockAMD64(h *[8]uint64, t *tables, p []byte)
@@ -0,0 +1,190 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// The key K and the cipher state S live on the stack at 0(SP) and
+// 64(SP); the round function output is accumulated in R8-R15. SI points
+// to the tables: c[k] at 2048*k and rc at 16384.
+
+// WORD xors the contributions of the word at off(SP) into r0-r7, where
+// byte k of the word, counted from the most significant one, is looked
+// up in c[k] and lands in rk.
+#define WORD(off, r0, r1, r2, r3, r4, r5, r6, r7) \
+	MOVQ    (off)(SP), AX;           \
+	MOVBQZX AX, BX;                  \
+	XORQ    14336(SI)(BX*8), r7;     \
+	SHRQ    $8, AX;                  \
+	MOVBQZX AX, BX;                  \
+	XORQ    12288(SI)(BX*8), r6;     \
+	SHRQ    $8, AX;                  \
+	MOVBQZX AX, BX;                  \
+	XORQ    10240(SI)(BX*8), r5;     \
+	SHRQ    $8, AX;                  \
+	MOVBQZX AX, BX;                  \
+	XORQ    8192(SI)(BX*8), r4;      \
+	SHRQ    $8, AX;                  \
+	MOVBQZX AX, BX;                  \
+	XORQ    6144(SI)(BX*8), r3;      \
+	SHRQ    $8, AX;                  \
+	MOVBQZX AX, BX;                  \
+	XORQ    4096(SI)(BX*8), r2;      \
+	SHRQ    $8, AX;                  \
+	MOVBQZX AX, BX;                  \
+	XORQ    2048(SI)(BX*8), r1;      \
+	SHRQ    $8, AX;                  \
+	XORQ    (SI)(AX*8), r0
+
+// ROUND computes the substitution, permutation and diffusion layers of
+// the matrix at base(SP), xoring them into R8-R15.
+#define ROUND(base) \
+	WORD((base)+0, R8, R9, R10, R11, R12, R13, R14, R15); \
+	WORD((base)+8, R9, R10, R11, R12, R13, R14, R15, R8); \
+	WORD((base)+16, R10, R11, R12, R13, R14, R15, R8, R9); \
+	WORD((base)+24, R11, R12, R13, R14, R15, R8, R9, R10); \
+	WORD((base)+32, R12, R13, R14, R15, R8, R9, R10, R11); \
+	WORD((base)+40, R13, R14, R15, R8, R9, R10, R11, R12); \
+	WORD((base)+48, R14, R15, R8, R9, R10, R11, R12, R13); \
+	WORD((base)+56, R15, R8, R9, R10, R11, R12, R13, R14)
+
+#define STORE(base) \
+	MOVQ R8, ((base)+0)(SP); \
+	MOVQ R9, ((base)+8)(SP); \
+	MOVQ R10, ((base)+16)(SP); \
+	MOVQ R11, ((base)+24)(SP); \
+	MOVQ R12, ((base)+32)(SP); \
+	MOVQ R13, ((base)+40)(SP); \
+	MOVQ R14, ((base)+48)(SP); \
+	MOVQ R15, ((base)+56)(SP)
+
+// func blockAMD64(h *[8]uint64, t *tables, p []byte)
+TEXT ·blockAMD64(SB), NOSPLIT, $128-40
+	MOVQ t+8(FP), SI
+	MOVQ p_base+16(FP), DX
+	MOVQ p_len+24(FP), CX
+
+loop:
+	CMPQ CX, $64
+	JB   done
+
+	// K = h, S = block ^ K
+	MOVQ h+0(FP), AX
+	MOVQ 0(AX), BX
+	MOVQ BX, 0(SP)
+	MOVQ 0(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 64(SP)
+	MOVQ 8(AX), BX
+	MOVQ BX, 8(SP)
+	MOVQ 8(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 72(SP)
+	MOVQ 16(AX), BX
+	MOVQ BX, 16(SP)
+	MOVQ 16(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 80(SP)
+	MOVQ 24(AX), BX
+	MOVQ BX, 24(SP)
+	MOVQ 24(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 88(SP)
+	MOVQ 32(AX), BX
+	MOVQ BX, 32(SP)
+	MOVQ 32(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 96(SP)
+	MOVQ 40(AX), BX
+	MOVQ BX, 40(SP)
+	MOVQ 40(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 104(SP)
+	MOVQ 48(AX), BX
+	MOVQ BX, 48(SP)
+	MOVQ 48(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 112(SP)
+	MOVQ 56(AX), BX
+	MOVQ BX, 56(SP)
+	MOVQ 56(DX), R8
+	BSWAPQ R8
+	XORQ BX, R8
+	MOVQ R8, 120(SP)
+
+	MOVQ $1, DI
+
+round:
+	// K = rho(K) ^ rc[r]
+	MOVQ 16384(SI)(DI*8), R8
+	XORQ R9, R9
+	XORQ R10, R10
+	XORQ R11, R11
+	XORQ R12, R12
+	XORQ R13, R13
+	XORQ R14, R14
+	XORQ R15, R15
+	ROUND(0)
+	STORE(0)
+
+	// S = rho(S) ^ K
+	MOVQ 0(SP), R8
+	MOVQ 8(SP), R9
+	MOVQ 16(SP), R10
+	MOVQ 24(SP), R11
+	MOVQ 32(SP), R12
+	MOVQ 40(SP), R13
+	MOVQ 48(SP), R14
+	MOVQ 56(SP), R15
+	ROUND(64)
+	STORE(64)
+	INCQ DI
+	CMPQ DI, $11
+	JB   round
+
+	// h ^= S ^ block
+	MOVQ h+0(FP), AX
+	MOVQ 0(DX), BX
+	BSWAPQ BX
+	XORQ 64(SP), BX
+	XORQ BX, 0(AX)
+	MOVQ 8(DX), BX
+	BSWAPQ BX
+	XORQ 72(SP), BX
+	XORQ BX, 8(AX)
+	MOVQ 16(DX), BX
+	BSWAPQ BX
+	XORQ 80(SP), BX
+	XORQ BX, 16(AX)
+	MOVQ 24(DX), BX
+	BSWAPQ BX
+	XORQ 88(SP), BX
+	XORQ BX, 24(AX)
+	MOVQ 32(DX), BX
+	BSWAPQ BX
+	XORQ 96(SP), BX
+	XORQ BX, 32(AX)
+	MOVQ 40(DX), BX
+	BSWAPQ BX
+	XORQ 104(SP), BX
+	XORQ BX, 40(AX)
+	MOVQ 48(DX), BX
+	BSWAPQ BX
+	XORQ 112(SP), BX
+	XORQ BX, 48(AX)
+	MOVQ 56(DX), BX
+	BSWAPQ BX
+	XORQ 120(SP), BX
+	XORQ BX, 56(AX)
+
+	ADDQ $64, DX
+	SUBQ $64, CX
+	JMP  loop
+
+done:
+	RET
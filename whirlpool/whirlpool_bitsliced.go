@@ -0,0 +1,308 @@
+package whirlpool
+
+// Constant-time Whirlpool. The cipher state is kept as eight bit planes,
+// plane b holding bit b of all 64 state bytes, so that the S-box becomes
+// a boolean circuit and the permutation and diffusion layers become
+// shifts and masks. No memory access depends on the hashed data.
+//
+// Byte (i, j) of the state, row i and column j, is bit 8*i+7-j of a
+// plane, so that each row occupies one byte of the plane.
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// anf is the algebraic normal form of an n-bit S-box: output bit b is
+// the XOR of the monomials in terms[b], monomial m being the AND of the
+// input bits set in m.
+type anf struct {
+	n     int
+	terms [8][]uint8
+}
+
+// newANF computes the algebraic normal form of sbox with the Moebius
+// transform.
+func newANF(sbox []byte) *anf {
+	f := &anf{}
+	for 1<<f.n < len(sbox) {
+		f.n++
+	}
+	coef := make([]byte, len(sbox))
+	for b := 0; b < f.n; b++ {
+		for x := range coef {
+			coef[x] = sbox[x] >> b & 1
+		}
+		for i := 0; i < f.n; i++ {
+			for x := range coef {
+				if x&(1<<i) != 0 {
+					coef[x] ^= coef[x^1<<i]
+				}
+			}
+		}
+		for m, c := range coef {
+			if c != 0 {
+				f.terms[b] = append(f.terms[b], uint8(m))
+			}
+		}
+	}
+	return f
+}
+
+// eval applies the S-box to the bit planes in, writing the bit planes
+// of the result to out, which may alias in. m is scratch space for the
+// 1<<n monomials.
+func (f *anf) eval(out, in, m []uint64) {
+	m[0] = ^uint64(0)
+	for i := 0; i < f.n; i++ {
+		for s := 0; s < 1<<i; s++ {
+			m[s|1<<i] = m[s] & in[i]
+		}
+	}
+	for b := 0; b < f.n; b++ {
+		var v uint64
+		for _, s := range f.terms[b] {
+			v ^= m[s]
+		}
+		out[b] = v
+	}
+}
+
+// miniBox is a 4-bit S-box in algebraic normal form: bit m of f[b] is
+// set if monomial m appears in output bit b.
+type miniBox [4]uint16
+
+func newMiniBox(sbox *[16]byte) *miniBox {
+	f := new(miniBox)
+	for b, terms := range newANF(sbox[:]).terms[:4] {
+		for _, m := range terms {
+			f[b] |= 1 << m
+		}
+	}
+	return f
+}
+
+// eval applies the S-box to the bit planes in, writing the bit planes
+// of the result to out, which may alias in.
+func (f *miniBox) eval(out, in *[4]uint64) {
+	var m [16]uint64
+	m[0] = ^uint64(0)
+	m[1] = in[0]
+	m[2] = in[1]
+	m[3] = in[0] & in[1]
+	for s := 0; s < 4; s++ {
+		m[4|s] = m[s] & in[2]
+	}
+	for s := 0; s < 8; s++ {
+		m[8|s] = m[s] & in[3]
+	}
+	for b := 0; b < 4; b++ {
+		var v uint64
+		// the loop only depends on the public S-box.
+		for terms := f[b]; terms != 0; terms &= terms - 1 {
+			v ^= m[bits.TrailingZeros16(terms)&15]
+		}
+		out[b] = v
+	}
+}
+
+var miniBoxE, miniBoxEInv, miniBoxR = newMiniBoxes()
+
+func newMiniBoxes() (e, eInv, r *miniBox) {
+	var inv [16]byte
+	for i, v := range miniE {
+		inv[v] = byte(i)
+	}
+	return newMiniBox(&miniE), newMiniBox(&inv), newMiniBox(&miniR)
+}
+
+// bitsliced holds what the constant-time implementation needs to know
+// about one Whirlpool variant.
+type bitsliced struct {
+	sbox *anf // the Whirlpool-0 S-box, nil for the tweaked S-box
+	circ [8]byte
+	rc   [cRounds + 1][8]uint64 // round constants as bit planes
+}
+
+var (
+	bitsliced2003 = newBitsliced(tables2003, circ2003, nil)
+	bitslicedT    = newBitsliced(tablesT, circ2000, nil)
+	bitsliced0    = newBitsliced(tables0, circ2000, newANF(sBox0[:]))
+)
+
+func (v Variant) bitsliced() *bitsliced {
+	switch v {
+	case Whirlpool:
+		return bitsliced2003
+	case WhirlpoolT:
+		return bitslicedT
+	case Whirlpool0:
+		return bitsliced0
+	}
+	panic("whirlpool: unknown variant")
+}
+
+func newBitsliced(t *tables, circ [8]byte, sbox *anf) *bitsliced {
+	bs := &bitsliced{sbox: sbox, circ: circ}
+	for _, m := range circ {
+		if m >= 16 {
+			// theta only computes the multiples by 1, 2, 4 and 8.
+			panic("whirlpool: diffusion matrix entry too large")
+		}
+	}
+	for r := 1; r <= cRounds; r++ {
+		toPlanes(&bs.rc[r], &[8]uint64{t.rc[r]})
+	}
+	return bs
+}
+
+// blockBitsliced is the constant-time compression function.
+func blockBitsliced(h *[8]uint64, bs *bitsliced, p []byte) {
+	var block, K, state, out [8]uint64
+	for len(p) >= cWBlockBytes {
+		for i := 0; i < 8; i++ {
+			block[i] = binary.BigEndian.Uint64(p[8*i:])
+		}
+		toPlanes(&K, h)
+		toPlanes(&state, &block)
+		for b := 0; b < 8; b++ {
+			state[b] ^= K[b]
+		}
+		for r := 1; r <= cRounds; r++ {
+			bs.round(&K, &bs.rc[r])
+			bs.round(&state, &K)
+		}
+		fromPlanes(&out, &state)
+		for i := 0; i < 8; i++ {
+			h[i] ^= out[i] ^ block[i]
+		}
+		p = p[cWBlockBytes:]
+	}
+}
+
+// round replaces x by the round function of x under key.
+func (bs *bitsliced) round(x, key *[8]uint64) {
+	bs.subBytes(x)
+	shiftColumns(x)
+	bs.mixRows(x)
+	for b := 0; b < 8; b++ {
+		x[b] ^= key[b]
+	}
+}
+
+// subBytes is the nonlinear layer gamma.
+func (bs *bitsliced) subBytes(x *[8]uint64) {
+	if bs.sbox != nil {
+		var m [256]uint64
+		bs.sbox.eval(x[:], x[:], m[:])
+		return
+	}
+	// the tweaked S-box is built from the 4-bit mini-boxes E and R:
+	var u, l, r [4]uint64
+	miniBoxE.eval(&u, (*[4]uint64)(x[4:]))
+	miniBoxEInv.eval(&l, (*[4]uint64)(x[:4]))
+	for i := 0; i < 4; i++ {
+		r[i] = u[i] ^ l[i]
+	}
+	miniBoxR.eval(&r, &r)
+	for i := 0; i < 4; i++ {
+		u[i] ^= r[i]
+		l[i] ^= r[i]
+	}
+	miniBoxE.eval((*[4]uint64)(x[4:]), &u)
+	miniBoxEInv.eval((*[4]uint64)(x[:4]), &l)
+}
+
+const bytesLSB = 0x0101010101010101
+
+// shiftColumns is the cyclical permutation pi, moving column j down by
+// j rows.
+func shiftColumns(x *[8]uint64) {
+	for b := 0; b < 8; b++ {
+		v := x[b]
+		var w uint64
+		for j := 0; j < 8; j++ {
+			w |= bits.RotateLeft64(v, 8*j) & (bytesLSB << (7 - j))
+		}
+		x[b] = w
+	}
+}
+
+// mixRows is the linear diffusion layer theta, multiplying each row by
+// the circulant matrix. Column j of the result is the sum of column j-d
+// times circ[d]; splitting the entries into powers of x, it is computed
+// as s0 + x*(s1 + x*(s2 + x*s3)), where sk sums the columns rotated by
+// every d whose entry has bit k set.
+func (bs *bitsliced) mixRows(x *[8]uint64) {
+	var s [4][8]uint64
+	for d, c := range bs.circ {
+		low := bytesLSB * uint64(0xff>>d)
+		high := bytesLSB * uint64(byte(0xff<<(8-d)))
+		var v [8]uint64
+		for b := 0; b < 8; b++ {
+			v[b] = x[b]>>d&low | x[b]<<(8-d)&high
+		}
+		for k := 0; k < 4; k++ {
+			if c>>k&1 != 0 {
+				for b := 0; b < 8; b++ {
+					s[k][b] ^= v[b]
+				}
+			}
+		}
+	}
+	out := s[3]
+	for k := 2; k >= 0; k-- {
+		xtime(&out, &out)
+		for b := 0; b < 8; b++ {
+			out[b] ^= s[k][b]
+		}
+	}
+	*x = out
+}
+
+// xtime multiplies every byte of src by x modulo the polynomial
+// x^8 + x^4 + x^3 + x^2 + 1. dst may alias src.
+func xtime(dst, src *[8]uint64) {
+	hi := src[7]
+	dst[7] = src[6]
+	dst[6] = src[5]
+	dst[5] = src[4]
+	dst[4] = src[3] ^ hi
+	dst[3] = src[2] ^ hi
+	dst[2] = src[1] ^ hi
+	dst[1] = src[0]
+	dst[0] = hi
+}
+
+// toPlanes converts the rows of a matrix to bit planes.
+func toPlanes(dst, rows *[8]uint64) {
+	*dst = [8]uint64{}
+	for i := 0; i < 8; i++ {
+		t := transpose8(rows[i])
+		for b := 0; b < 8; b++ {
+			dst[b] |= (t >> (8 * b) & 0xff) << (8 * i)
+		}
+	}
+}
+
+// fromPlanes converts bit planes back to the rows of a matrix.
+func fromPlanes(dst, planes *[8]uint64) {
+	for i := 0; i < 8; i++ {
+		var t uint64
+		for b := 0; b < 8; b++ {
+			t |= (planes[b] >> (8 * i) & 0xff) << (8 * b)
+		}
+		dst[i] = transpose8(t)
+	}
+}
+
+// transpose8 transposes x seen as an 8x8 bit matrix, moving bit 8r+c
+// to bit 8c+r.
+func transpose8(x uint64) uint64 {
+	t := (x ^ x>>7) & 0x00aa00aa00aa00aa
+	x ^= t ^ t<<7
+	t = (x ^ x>>14) & 0x0000cccc0000cccc
+	x ^= t ^ t<<14
+	t = (x ^ x>>28) & 0x00000000f0f0f0f0
+	x ^= t ^ t<<28
+	return x
+}
@@ -0,0 +1,270 @@
+package whirlpool
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// compress runs the compression function over the whole blocks of p.
+func (ob *Hash) compress(p []byte) {
+	t := ob.t
+	if t == nil {
+		t = tables2003
+	}
+	switch {
+	case ob.tracer != nil:
+		ob.blockTraced(t, p)
+	case ob.bs != nil:
+		blockBitsliced(&ob.hash, ob.bs, p)
+	case ob.compact:
+		blockCompact(&ob.hash, t, p)
+	default:
+		block(&ob.hash, t, p)
+	}
+}
+
+// blockGeneric is the pure Go compression function. It keeps the key
+// and the cipher state in locals so that they can live in registers,
+// and uses one lookup table per byte position.
+func blockGeneric(h *[8]uint64, t *tables, p []byte) {
+	c0, c1, c2, c3 := &t.c[0], &t.c[1], &t.c[2], &t.c[3]
+	c4, c5, c6, c7 := &t.c[4], &t.c[5], &t.c[6], &t.c[7]
+	rc := &t.rc
+	for len(p) >= cWBlockBytes {
+		// map the buffer to a block and apply K^0 to the cipher state:
+		k0, k1, k2, k3 := h[0], h[1], h[2], h[3]
+		k4, k5, k6, k7 := h[4], h[5], h[6], h[7]
+		s0 := binary.BigEndian.Uint64(p[0:]) ^ k0
+		s1 := binary.BigEndian.Uint64(p[8:]) ^ k1
+		s2 := binary.BigEndian.Uint64(p[16:]) ^ k2
+		s3 := binary.BigEndian.Uint64(p[24:]) ^ k3
+		s4 := binary.BigEndian.Uint64(p[32:]) ^ k4
+		s5 := binary.BigEndian.Uint64(p[40:]) ^ k5
+		s6 := binary.BigEndian.Uint64(p[48:]) ^ k6
+		s7 := binary.BigEndian.Uint64(p[56:]) ^ k7
+		for r := 1; r <= cRounds; r++ {
+			// compute K^r from K^{r-1}, then apply it to the state:
+			l0 := c0[k0>>56] ^
+				c1[byte(k7>>48)] ^
+				c2[byte(k6>>40)] ^
+				c3[byte(k5>>32)] ^
+				c4[byte(k4>>24)] ^
+				c5[byte(k3>>16)] ^
+				c6[byte(k2>>8)] ^
+				c7[byte(k1)] ^
+				rc[r]
+			l1 := c0[k1>>56] ^
+				c1[byte(k0>>48)] ^
+				c2[byte(k7>>40)] ^
+				c3[byte(k6>>32)] ^
+				c4[byte(k5>>24)] ^
+				c5[byte(k4>>16)] ^
+				c6[byte(k3>>8)] ^
+				c7[byte(k2)]
+			l2 := c0[k2>>56] ^
+				c1[byte(k1>>48)] ^
+				c2[byte(k0>>40)] ^
+				c3[byte(k7>>32)] ^
+				c4[byte(k6>>24)] ^
+				c5[byte(k5>>16)] ^
+				c6[byte(k4>>8)] ^
+				c7[byte(k3)]
+			l3 := c0[k3>>56] ^
+				c1[byte(k2>>48)] ^
+				c2[byte(k1>>40)] ^
+				c3[byte(k0>>32)] ^
+				c4[byte(k7>>24)] ^
+				c5[byte(k6>>16)] ^
+				c6[byte(k5>>8)] ^
+				c7[byte(k4)]
+			l4 := c0[k4>>56] ^
+				c1[byte(k3>>48)] ^
+				c2[byte(k2>>40)] ^
+				c3[byte(k1>>32)] ^
+				c4[byte(k0>>24)] ^
+				c5[byte(k7>>16)] ^
+				c6[byte(k6>>8)] ^
+				c7[byte(k5)]
+			l5 := c0[k5>>56] ^
+				c1[byte(k4>>48)] ^
+				c2[byte(k3>>40)] ^
+				c3[byte(k2>>32)] ^
+				c4[byte(k1>>24)] ^
+				c5[byte(k0>>16)] ^
+				c6[byte(k7>>8)] ^
+				c7[byte(k6)]
+			l6 := c0[k6>>56] ^
+				c1[byte(k5>>48)] ^
+				c2[byte(k4>>40)] ^
+				c3[byte(k3>>32)] ^
+				c4[byte(k2>>24)] ^
+				c5[byte(k1>>16)] ^
+				c6[byte(k0>>8)] ^
+				c7[byte(k7)]
+			l7 := c0[k7>>56] ^
+				c1[byte(k6>>48)] ^
+				c2[byte(k5>>40)] ^
+				c3[byte(k4>>32)] ^
+				c4[byte(k3>>24)] ^
+				c5[byte(k2>>16)] ^
+				c6[byte(k1>>8)] ^
+				c7[byte(k0)]
+			k0, k1, k2, k3, k4, k5, k6, k7 = l0, l1, l2, l3, l4, l5, l6, l7
+			l0 = c0[s0>>56] ^
+				c1[byte(s7>>48)] ^
+				c2[byte(s6>>40)] ^
+				c3[byte(s5>>32)] ^
+				c4[byte(s4>>24)] ^
+				c5[byte(s3>>16)] ^
+				c6[byte(s2>>8)] ^
+				c7[byte(s1)] ^
+				k0
+			l1 = c0[s1>>56] ^
+				c1[byte(s0>>48)] ^
+				c2[byte(s7>>40)] ^
+				c3[byte(s6>>32)] ^
+				c4[byte(s5>>24)] ^
+				c5[byte(s4>>16)] ^
+				c6[byte(s3>>8)] ^
+				c7[byte(s2)] ^
+				k1
+			l2 = c0[s2>>56] ^
+				c1[byte(s1>>48)] ^
+				c2[byte(s0>>40)] ^
+				c3[byte(s7>>32)] ^
+				c4[byte(s6>>24)] ^
+				c5[byte(s5>>16)] ^
+				c6[byte(s4>>8)] ^
+				c7[byte(s3)] ^
+				k2
+			l3 = c0[s3>>56] ^
+				c1[byte(s2>>48)] ^
+				c2[byte(s1>>40)] ^
+				c3[byte(s0>>32)] ^
+				c4[byte(s7>>24)] ^
+				c5[byte(s6>>16)] ^
+				c6[byte(s5>>8)] ^
+				c7[byte(s4)] ^
+				k3
+			l4 = c0[s4>>56] ^
+				c1[byte(s3>>48)] ^
+				c2[byte(s2>>40)] ^
+				c3[byte(s1>>32)] ^
+				c4[byte(s0>>24)] ^
+				c5[byte(s7>>16)] ^
+				c6[byte(s6>>8)] ^
+				c7[byte(s5)] ^
+				k4
+			l5 = c0[s5>>56] ^
+				c1[byte(s4>>48)] ^
+				c2[byte(s3>>40)] ^
+				c3[byte(s2>>32)] ^
+				c4[byte(s1>>24)] ^
+				c5[byte(s0>>16)] ^
+				c6[byte(s7>>8)] ^
+				c7[byte(s6)] ^
+				k5
+			l6 = c0[s6>>56] ^
+				c1[byte(s5>>48)] ^
+				c2[byte(s4>>40)] ^
+				c3[byte(s3>>32)] ^
+				c4[byte(s2>>24)] ^
+				c5[byte(s1>>16)] ^
+				c6[byte(s0>>8)] ^
+				c7[byte(s7)] ^
+				k6
+			l7 = c0[s7>>56] ^
+				c1[byte(s6>>48)] ^
+				c2[byte(s5>>40)] ^
+				c3[byte(s4>>32)] ^
+				c4[byte(s3>>24)] ^
+				c5[byte(s2>>16)] ^
+				c6[byte(s1>>8)] ^
+				c7[byte(s0)] ^
+				k7
+			s0, s1, s2, s3, s4, s5, s6, s7 = l0, l1, l2, l3, l4, l5, l6, l7
+		}
+		// apply the Miyaguchi-Preneel compression function:
+		h[0] ^= s0 ^ binary.BigEndian.Uint64(p[0:])
+		h[1] ^= s1 ^ binary.BigEndian.Uint64(p[8:])
+		h[2] ^= s2 ^ binary.BigEndian.Uint64(p[16:])
+		h[3] ^= s3 ^ binary.BigEndian.Uint64(p[24:])
+		h[4] ^= s4 ^ binary.BigEndian.Uint64(p[32:])
+		h[5] ^= s5 ^ binary.BigEndian.Uint64(p[40:])
+		h[6] ^= s6 ^ binary.BigEndian.Uint64(p[48:])
+		h[7] ^= s7 ^ binary.BigEndian.Uint64(p[56:])
+		p = p[cWBlockBytes:]
+	}
+}
+
+// blockCompact is the compression function working from the first
+// lookup table only, deriving the others by rotation. It is slower than
+// blockGeneric but touches 2 KiB of tables instead of 16 KiB.
+func blockCompact(h *[8]uint64, t *tables, p []byte) {
+	var block, K, state, L [8]uint64
+	c := &t.c[0]
+	for len(p) >= cWBlockBytes {
+		for i := 0; i < 8; i++ {
+			block[i] = binary.BigEndian.Uint64(p[8*i:])
+			K[i] = h[i]
+			state[i] = block[i] ^ K[i]
+		}
+		for r := 1; r <= cRounds; r++ {
+			compactRound(c, &L, &K)
+			K = L
+			K[0] ^= t.rc[r]
+			compactRound(c, &L, &state)
+			for i := 0; i < 8; i++ {
+				state[i] = L[i] ^ K[i]
+			}
+		}
+		for i := 0; i < 8; i++ {
+			h[i] ^= state[i] ^ block[i]
+		}
+		p = p[cWBlockBytes:]
+	}
+}
+
+// compactRound applies the substitution, permutation and diffusion
+// layers to src using the single table c, since t.c[k][x] is t.c[0][x]
+// rotated right by 8k bits.
+func compactRound(c *[256]uint64, dst, src *[8]uint64) {
+	for i := 0; i < 8; i++ {
+		dst[i] = c[src[i]>>56] ^
+			bits.RotateLeft64(c[byte(src[(i+7)&7]>>48)], -8) ^
+			bits.RotateLeft64(c[byte(src[(i+6)&7]>>40)], -16) ^
+			bits.RotateLeft64(c[byte(src[(i+5)&7]>>32)], -24) ^
+			bits.RotateLeft64(c[byte(src[(i+4)&7]>>24)], -32) ^
+			bits.RotateLeft64(c[byte(src[(i+3)&7]>>16)], -40) ^
+			bits.RotateLeft64(c[byte(src[(i+2)&7]>>8)], -48) ^
+			bits.RotateLeft64(c[byte(src[(i+1)&7])], -56)
+	}
+}
+
+// blockTraced is the straightforward compression function, reporting
+// every intermediate value to ob.tracer.
+func (ob *Hash) blockTraced(t *tables, p []byte) {
+	var block, K, state, L, rcKey [8]uint64
+	tr := ob.tracer
+	for len(p) >= cWBlockBytes {
+		for i := 0; i < 8; i++ {
+			block[i] = binary.BigEndian.Uint64(p[8*i:])
+			K[i] = ob.hash[i]
+			state[i] = block[i] ^ K[i]
+		}
+		tr.Block(Matrix(block), Matrix(K), Matrix(state))
+		for r := 1; r <= cRounds; r++ {
+			rcKey[0] = t.rc[r]
+			t.round(&L, &K, &rcKey)
+			K = L
+			t.round(&L, &state, &K)
+			state = L
+			tr.Round(r, Matrix(K), Matrix(state))
+		}
+		for i := 0; i < 8; i++ {
+			ob.hash[i] ^= state[i] ^ block[i]
+		}
+		tr.Output(Matrix(ob.hash))
+		p = p[cWBlockBytes:]
+	}
+}
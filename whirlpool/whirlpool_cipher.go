@@ -0,0 +1,241 @@
+package whirlpool
+
+//  The W block cipher underlying the Whirlpool compression function.
+//
+//  W is a 512-bit block cipher with a 512-bit key, ten rounds and the
+//  same round function for the key schedule and the data path:
+//    P.S.L.M. Barreto, V. Rijmen,
+//    ``The Whirlpool hashing function,'' section 5.
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"strconv"
+)
+
+// The block and key size of the W cipher in bytes.
+const (
+	CipherBlockSize = cWBlockBytes
+	CipherKeySize   = cDigestBytes
+)
+
+// KeySizeError is returned by NewCipher for keys of the wrong length.
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "whirlpool: invalid W key size " + strconv.Itoa(int(k))
+}
+
+// wCipher is an instance of W with an expanded key.
+type wCipher struct {
+	t   *tables
+	inv *inverseTables
+	k   [cRounds + 1][8]uint64 // round keys K^0 .. K^10
+}
+
+// NewCipher creates and returns a new cipher.Block implementing the W
+// block cipher of the final Whirlpool. The key must be 64 bytes long.
+// Encrypting a block under a chaining value as key and XORing the
+// result with the block and the key computes one Whirlpool
+// compression.
+func NewCipher(key []byte) (cipher.Block, error) {
+	return NewVariantCipher(Whirlpool, key)
+}
+
+// NewVariantCipher creates and returns a new cipher.Block implementing
+// the W block cipher of the given Whirlpool revision.
+func NewVariantCipher(v Variant, key []byte) (cipher.Block, error) {
+	if len(key) != CipherKeySize {
+		return nil, KeySizeError(len(key))
+	}
+	if err := selfTest.Check(); err != nil {
+		return nil, err
+	}
+	return newCipher(v, key), nil
+}
+
+// newCipher expands key, which must be CipherKeySize bytes long.
+func newCipher(v Variant, key []byte) *wCipher {
+	c := &wCipher{t: v.tables(), inv: v.inverseTables()}
+	for i := range c.k[0] {
+		c.k[0][i] = binary.BigEndian.Uint64(key[8*i:])
+	}
+	for r := 1; r <= cRounds; r++ {
+		var rc [8]uint64
+		rc[0] = c.t.rc[r]
+		c.t.round(&c.k[r], &c.k[r-1], &rc)
+	}
+	return c
+}
+
+func (c *wCipher) BlockSize() int { return CipherBlockSize }
+
+func (c *wCipher) Encrypt(dst, src []byte) {
+	checkBlock(dst, src)
+	var state, l [8]uint64
+	for i := range state {
+		state[i] = binary.BigEndian.Uint64(src[8*i:]) ^ c.k[0][i]
+	}
+	for r := 1; r <= cRounds; r++ {
+		c.t.round(&l, &state, &c.k[r])
+		state = l
+	}
+	for i := range state {
+		binary.BigEndian.PutUint64(dst[8*i:], state[i])
+	}
+}
+
+func (c *wCipher) Decrypt(dst, src []byte) {
+	checkBlock(dst, src)
+	var state, l [8]uint64
+	for i := range state {
+		state[i] = binary.BigEndian.Uint64(src[8*i:])
+	}
+	for r := cRounds; r >= 1; r-- {
+		c.inv.round(&l, &state, &c.k[r])
+		state = l
+	}
+	for i := range state {
+		binary.BigEndian.PutUint64(dst[8*i:], state[i]^c.k[0][i])
+	}
+}
+
+func checkBlock(dst, src []byte) {
+	if len(src) < CipherBlockSize {
+		panic("whirlpool: input not full block")
+	}
+	if len(dst) < CipherBlockSize {
+		panic("whirlpool: output not full block")
+	}
+}
+
+// round computes dst = theta(pi(gamma(src))) ^ key.
+func (t *tables) round(dst, src, key *[8]uint64) {
+	for i := 0; i < 8; i++ {
+		dst[i] = t.c[0][src[i]>>56] ^
+			t.c[1][byte(src[(i+7)&7]>>48)] ^
+			t.c[2][byte(src[(i+6)&7]>>40)] ^
+			t.c[3][byte(src[(i+5)&7]>>32)] ^
+			t.c[4][byte(src[(i+4)&7]>>24)] ^
+			t.c[5][byte(src[(i+3)&7]>>16)] ^
+			t.c[6][byte(src[(i+2)&7]>>8)] ^
+			t.c[7][byte(src[(i+1)&7])] ^
+			key[i]
+	}
+}
+
+// inverseTables holds what is needed to invert the round function of
+// one Whirlpool variant.
+type inverseTables struct {
+	sbox [256]byte
+	// d[k][x] is x multiplied by row k of the inverse diffusion matrix.
+	d [8][256]uint64
+}
+
+var (
+	inverseTables2003 = newInverseTables(tables2003, circ2003)
+	inverseTablesT    = newInverseTables(tablesT, circ2000)
+	inverseTables0    = newInverseTables(tables0, circ2000)
+)
+
+func (v Variant) inverseTables() *inverseTables {
+	switch v {
+	case Whirlpool:
+		return inverseTables2003
+	case WhirlpoolT:
+		return inverseTablesT
+	case Whirlpool0:
+		return inverseTables0
+	}
+	panic("whirlpool: unknown variant")
+}
+
+func newInverseTables(t *tables, circ [8]byte) *inverseTables {
+	inv := new(inverseTables)
+	for x := 0; x < 256; x++ {
+		inv.sbox[byte(t.c[0][x]>>56)] = byte(x)
+	}
+	// the inverse of a circulant matrix is circulant; row k is the
+	// first row rotated right by k.
+	first := invertCirculant(circ)
+	for x := 0; x < 256; x++ {
+		for k := 0; k < 8; k++ {
+			var v uint64
+			for j := 0; j < 8; j++ {
+				v = v<<8 | uint64(gfMul(byte(x), first[(j-k+8)&7]))
+			}
+			inv.d[k][x] = v
+		}
+	}
+	return inv
+}
+
+// round computes dst = gamma^-1(pi^-1(theta^-1(src ^ key))).
+func (inv *inverseTables) round(dst, src, key *[8]uint64) {
+	var l [8]uint64
+	for i := 0; i < 8; i++ {
+		w := src[i] ^ key[i]
+		l[i] = inv.d[0][w>>56] ^
+			inv.d[1][byte(w>>48)] ^
+			inv.d[2][byte(w>>40)] ^
+			inv.d[3][byte(w>>32)] ^
+			inv.d[4][byte(w>>24)] ^
+			inv.d[5][byte(w>>16)] ^
+			inv.d[6][byte(w>>8)] ^
+			inv.d[7][byte(w)]
+	}
+	// pi shifted column j down by j rows; shift it back up.
+	for i := 0; i < 8; i++ {
+		var v uint64
+		for j := 0; j < 8; j++ {
+			b := byte(l[(i+j)&7] >> uint(56-8*j))
+			v = v<<8 | uint64(inv.sbox[b])
+		}
+		dst[i] = v
+	}
+}
+
+// invertCirculant returns the first row of the inverse of the
+// circulant matrix with the given first row, by Gauss-Jordan
+// elimination over GF(2^8).
+func invertCirculant(circ [8]byte) [8]byte {
+	var m, inv [8][8]byte
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			m[i][j] = circ[(j-i+8)&7]
+		}
+		inv[i][i] = 1
+	}
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for m[pivot][col] == 0 {
+			pivot++
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		f := gfInv(m[col][col])
+		for j := 0; j < 8; j++ {
+			m[col][j] = gfMul(m[col][j], f)
+			inv[col][j] = gfMul(inv[col][j], f)
+		}
+		for i := 0; i < 8; i++ {
+			if i == col || m[i][col] == 0 {
+				continue
+			}
+			f := m[i][col]
+			for j := 0; j < 8; j++ {
+				m[i][j] ^= gfMul(m[col][j], f)
+				inv[i][j] ^= gfMul(inv[col][j], f)
+			}
+		}
+	}
+	return inv[0]
+}
+
+// gfInv returns the multiplicative inverse of a non-zero a, a^254.
+func gfInv(a byte) byte {
+	p := byte(1)
+	for i := 0; i < 254; i++ {
+		p = gfMul(p, a)
+	}
+	return p
+}
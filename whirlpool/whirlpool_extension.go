@@ -0,0 +1,55 @@
+package whirlpool
+
+// Length extension of Whirlpool, for auditing legacy H(secret || message)
+// MACs. The Whirlpool digest is the full chaining value, so anyone
+// knowing H(m) and len(m) can compute H(m || padding || suffix)
+// without knowing m.
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+// NewFromDigest returns a hash.Hash resuming from sum, the published
+// Whirlpool digest of an unknown message of origLen bytes, together
+// with the glue padding that the original computation appended to
+// that message.
+//
+// Writing a suffix to the returned hash and calling Sum yields the
+// digest of message || glue || suffix. This is an attack primitive,
+// meant only for demonstrating the forgery against systems under
+// test; never use Whirlpool as a MAC this way.
+func NewFromDigest(sum []byte, origLen uint64) (hash.Hash, []byte, error) {
+	if len(sum) != Size {
+		return nil, nil, errors.New("whirlpool: invalid digest length")
+	}
+	if err := selfTest.Check(); err != nil {
+		return nil, nil, err
+	}
+	glue := padding(origLen)
+	ob := new(Hash)
+	for i := range ob.hash {
+		ob.hash[i] = binary.BigEndian.Uint64(sum[8*i:])
+	}
+	putBitLength(&ob.bitLength, origLen+uint64(len(glue)))
+	return ob, glue, nil
+}
+
+// padding returns the bytes appended by Sum to a message of n bytes: a
+// 1 bit, zeros, and the 256-bit length of the message in bits.
+func padding(n uint64) []byte {
+	pad := make([]byte, 0, 2*cWBlockBytes)
+	pad = append(pad, 0x80)
+	for (n+uint64(len(pad)))%cWBlockBytes != cWBlockBytes-cLengthBytes {
+		pad = append(pad, 0)
+	}
+	var length [cLengthBytes]byte
+	putBitLength(&length, n)
+	return append(pad, length[:]...)
+}
+
+// putBitLength stores the length in bits of n bytes, big-endian.
+func putBitLength(length *[cLengthBytes]byte, n uint64) {
+	binary.BigEndian.PutUint64(length[cLengthBytes-8:], n<<3)
+	length[cLengthBytes-9] = byte(n >> 61)
+}
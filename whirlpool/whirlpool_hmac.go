@@ -0,0 +1,55 @@
+package whirlpool
+
+//  Keyed and salted Whirlpool.
+//
+//  HMAC-Whirlpool is MAC algorithm 2 of ISO/IEC 9797-2:2011 and
+//  RFC 2104 instantiated with Whirlpool.
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"hash"
+)
+
+// NewHMAC returns a new hash.Hash computing HMAC-Whirlpool with the
+// given key.
+func NewHMAC(key []byte) hash.Hash {
+	return hmac.New(New, key)
+}
+
+// MAC returns the HMAC-Whirlpool of message under key.
+func MAC(key, message []byte) []byte {
+	mac := NewHMAC(key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// ValidMAC reports whether messageMAC is the HMAC-Whirlpool of message
+// under key, in constant time.
+func ValidMAC(key, message, messageMAC []byte) bool {
+	return hmac.Equal(MAC(key, message), messageMAC)
+}
+
+// NewSalted returns a new hash.Hash computing the Whirlpool checksum of
+// the 64-bit big-endian length of salt, salt, and then the data
+// written to it. Prefixing the length makes the salt and the data
+// unambiguous, so the digests differ from those of HashOfBytes.
+// The salt is not a key; use NewHMAC when the salt must stay secret.
+func NewSalted(salt []byte) hash.Hash {
+	selfTest.MustCheck()
+	s := &salted{salt: append([]byte(nil), salt...)}
+	s.Reset()
+	return s
+}
+
+type salted struct {
+	Hash
+	salt []byte
+}
+
+func (s *salted) Reset() {
+	s.Hash.Reset()
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(s.salt)))
+	s.Hash.Write(n[:])
+	s.Hash.Write(s.salt)
+}
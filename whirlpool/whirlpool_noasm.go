@@ -0,0 +1,7 @@
+//go:build !amd64 || purego
+
+package whirlpool
+
+func block(h *[8]uint64, t *tables, p []byte) {
+	blockGeneric(h, t, p)
+}
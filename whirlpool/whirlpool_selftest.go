@@ -0,0 +1,61 @@
+package whirlpool
+
+// Known-answer tests run before first use, see package selftest. The
+// tests bypass the constructors, which wait for them.
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/y3sh/go-legacy-crypto/selftest"
+)
+
+var selfTest = selftest.Register("Whirlpool", func() error {
+	const fox = "The quick brown fox jumps over the lazy dog"
+	vectors := []struct {
+		v      Variant
+		digest string
+	}{
+		{Whirlpool, "b97de512e91e3828b40d2b0fdce9ceb3c4a71f9bea8d88e75c4fa854df36725fd2b52eb6544edcacd6f8beddfea403cb55ae31f03ad62a5ef54e42ee82c3fb35"},
+		{WhirlpoolT, "3ccf8252d8bbb258460d9aa999c06ee38e67cb546cffcf48e91f700f6fc7c183ac8cc3d3096dd30a35b01f4620a1e3a20d79cd5168544d9e1b7cdf49970e87f1"},
+		{Whirlpool0, "4f8f5cb531e3d49a61cf417cd133792ccfa501fd8da53ee368fed20e5fe0248c3a0b64f98a6533cee1da614c3a8ddec791ff05fee6d971d57c1348320f4eb42d"},
+	}
+	for _, vec := range vectors {
+		expected, _ := hex.DecodeString(vec.digest)
+		for _, opt := range []Option{nil, WithCompactTables(), WithConstantTime()} {
+			h := &Hash{t: vec.v.tables()}
+			if opt != nil {
+				opt(h)
+			}
+			h.Write([]byte(fox))
+			if !bytes.Equal(h.Sum(nil), expected) {
+				return fmt.Errorf("wrong %v digest", vec.v)
+			}
+		}
+	}
+	// one compression is W encryption XORed with the block and the key.
+	key, block := make([]byte, CipherKeySize), make([]byte, CipherBlockSize)
+	for i := range key {
+		key[i], block[i] = byte(i), byte(3*i)
+	}
+	c := newCipher(Whirlpool, key)
+	ct, pt := make([]byte, CipherBlockSize), make([]byte, CipherBlockSize)
+	c.Encrypt(ct, block)
+	var h [8]uint64
+	for i := range h {
+		h[i] = binary.BigEndian.Uint64(key[8*i:])
+	}
+	blockGeneric(&h, tables2003, block)
+	for i := range ct {
+		if ct[i]^block[i]^key[i] != byte(h[i/8]>>(56-8*(i%8))) {
+			return errors.New("wrong W encryption")
+		}
+	}
+	c.Decrypt(pt, ct)
+	if !bytes.Equal(pt, block) {
+		return errors.New("wrong W decryption")
+	}
+	return nil
+})
@@ -0,0 +1,78 @@
+package whirlpool
+
+// Serialization of the partial evaluation of a Whirlpool checksum, in
+// the style of crypto/sha256.
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	magic         = "wrp\x02"
+	marshaledSize = len(magic) + 2 + cDigestBytes + cWBlockBytes + 2 + cLengthBytes
+)
+
+// variant returns the Whirlpool revision computed by ob.
+func (ob *Hash) variant() Variant {
+	switch ob.t {
+	case tablesT:
+		return WhirlpoolT
+	case tables0:
+		return Whirlpool0
+	}
+	return Whirlpool
+}
+
+// MarshalBinary encodes the partial evaluation of the checksum,
+// including any bits written with WriteBits, so that hashing can be
+// resumed with UnmarshalBinary. The tracer is not encoded.
+func (ob *Hash) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, marshaledSize)
+	b = append(b, magic...)
+	b = append(b, byte(ob.variant()), byte(ob.Size()))
+	for _, h := range ob.hash {
+		b = binary.BigEndian.AppendUint64(b, h)
+	}
+	used := ob.bufferPos
+	if ob.bufferBits&7 != 0 {
+		used++ // the partial byte
+	}
+	b = append(b, ob.buffer[:used]...)
+	b = b[:len(b)+cWBlockBytes-used] // already zero
+	b = binary.BigEndian.AppendUint16(b, uint16(ob.bufferBits))
+	b = append(b, ob.bitLength[:]...)
+	return b, nil
+}
+
+// UnmarshalBinary restores a state encoded by MarshalBinary. The state
+// must have been produced by a Hash of the same variant and size.
+func (ob *Hash) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("whirlpool: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize {
+		return errors.New("whirlpool: invalid hash state size")
+	}
+	b = b[len(magic):]
+	if Variant(b[0]) != ob.variant() {
+		return errors.New("whirlpool: hash state of a different variant")
+	}
+	if int(b[1]) != ob.Size() {
+		return errors.New("whirlpool: hash state of a different size")
+	}
+	b = b[2:]
+	bufferBits := int(binary.BigEndian.Uint16(b[cDigestBytes+cWBlockBytes:]))
+	if bufferBits >= cDigestBits {
+		return errors.New("whirlpool: invalid hash state buffer length")
+	}
+	for i := range ob.hash {
+		ob.hash[i] = binary.BigEndian.Uint64(b)
+		b = b[8:]
+	}
+	b = b[copy(ob.buffer[:], b):]
+	ob.bufferBits = bufferBits
+	ob.bufferPos = bufferBits / 8
+	b = b[2:]
+	copy(ob.bitLength[:], b)
+	return nil
+}
@@ -1,8 +1,20 @@
 package whirlpool
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/rand"
 	"strings"
 	"testing"
+
+	"github.com/y3sh/go-legacy-crypto/hashtest"
+	"github.com/y3sh/go-legacy-crypto/selftest"
 )
 
 func TestWhirlpoolHashing(t *testing.T) {
@@ -24,3 +36,765 @@ func assertWhirlpoolHash(t *testing.T, input, expectedOutput string) {
 		t.Errorf("Expected %v, got %v", expectedOutput, actual)
 	}
 }
+
+func TestWhirlpoolNew(t *testing.T) {
+	var h hash.Hash = New()
+	if h.Size() != Size || h.BlockSize() != BlockSize {
+		t.Errorf("Expected sizes %d/%d, got %d/%d", Size, BlockSize, h.Size(), h.BlockSize())
+	}
+	io.Copy(h, strings.NewReader("abc"))
+	assertWhirlpoolSum(t, h, "4E2448A4C6F486BB16B6562C73B4020BF3043E3A731BCE721AE1B303D97E6D4C7181EEBDB6C57E277D0E34957114CBD6C797FC9D95D8B582D225292076D4EEF5")
+
+	h.Reset()
+	assertWhirlpoolSum(t, h, "19FA61D75522A4669B44E39C1D2E1726C530232130D407F89AFEE0964997F7A73E83BE698B288FEBCF88E3E03C4F0757EA8964E59B63D93708B138CC42A66EB3")
+}
+
+func TestWhirlpoolSumIsNonDestructive(t *testing.T) {
+	h := New()
+	h.Write([]byte("abcdefghijklm"))
+	first := h.Sum(nil)
+	if !bytes.Equal(first, h.Sum(nil)) {
+		t.Errorf("Expected repeated Sum to be stable")
+	}
+	h.Write([]byte("nopqrstuvwxyz"))
+	assertWhirlpoolSum(t, h, "F1D754662636FFE92C82EBB9212A484A8D38631EAD4238F5442EE13B8054E41B08BF2A9251C30B6A0B8AAE86177AB4A6F68F673E7207865D5D9819A3DBA4EB3B")
+
+	prefix := []byte("prefix")
+	sum := h.Sum(prefix)
+	if !bytes.HasPrefix(sum, prefix) || len(sum) != len(prefix)+Size {
+		t.Errorf("Expected Sum to append to its input, got %x", sum)
+	}
+}
+
+func TestWhirlpoolWithHMAC(t *testing.T) {
+	mac := hmac.New(New, []byte("key"))
+	mac.Write([]byte("The quick brown fox jumps over the lazy dog"))
+	first := mac.Sum(nil)
+	mac.Reset()
+	mac.Write([]byte("The quick brown fox jumps over the lazy dog"))
+	if !hmac.Equal(first, mac.Sum(nil)) {
+		t.Errorf("Expected HMAC to be reproducible after Reset")
+	}
+}
+
+func assertWhirlpoolSum(t *testing.T, h hash.Hash, expectedOutput string) {
+	actual := strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+	if expectedOutput != actual {
+		t.Errorf("Expected %v, got %v", expectedOutput, actual)
+	}
+}
+
+func TestWhirlpoolVariants(t *testing.T) {
+	// http://www.larc.usp.br/~pbarreto/WhirlpoolPage.html
+	fox := "The quick brown fox jumps over the lazy dog"
+	assertVariantHash(t, Whirlpool0, "", "B3E1AB6EAF640A34F784593F2074416ACCD3B8E62C620175FCA0997B1BA2347339AA0D79E754C308209EA36811DFA40C1C32F1A2B9004725D987D3635165D3C8")
+	assertVariantHash(t, Whirlpool0, fox, "4F8F5CB531E3D49A61CF417CD133792CCFA501FD8DA53EE368FED20E5FE0248C3A0B64F98A6533CEE1DA614C3A8DDEC791FF05FEE6D971D57C1348320F4EB42D")
+	assertVariantHash(t, WhirlpoolT, "", "470F0409ABAA446E49667D4EBE12A14387CEDBD10DD17B8243CAD550A089DC0FEEA7AA40F6C2AAAB71C6EBD076E43C7CFCA0AD32567897DCB5969861049A0F5A")
+	assertVariantHash(t, WhirlpoolT, fox, "3CCF8252D8BBB258460D9AA999C06EE38E67CB546CFFCF48E91F700F6FC7C183AC8CC3D3096DD30A35B01F4620A1E3A20D79CD5168544D9E1B7CDF49970E87F1")
+	assertVariantHash(t, Whirlpool, "", "19FA61D75522A4669B44E39C1D2E1726C530232130D407F89AFEE0964997F7A73E83BE698B288FEBCF88E3E03C4F0757EA8964E59B63D93708B138CC42A66EB3")
+	assertVariantHash(t, Whirlpool, fox, "B97DE512E91E3828B40D2B0FDCE9CEB3C4A71F9BEA8D88E75C4FA854DF36725FD2B52EB6544EDCACD6F8BEDDFEA403CB55AE31F03AD62A5EF54E42EE82C3FB35")
+}
+
+func TestGeneratedTables(t *testing.T) {
+	// spot checks against the tables listed in the reference implementation
+	if tables2003.c[0][0] != 0x18186018c07830d8 || tables2003.c[7][1] != 0x238c2305af462623 {
+		t.Errorf("Unexpected circulant table entries %x %x", tables2003.c[0][0], tables2003.c[7][1])
+	}
+	if tables2003.rc[1] != 0x1823c6e887b8014f || tables2003.rc[10] != 0xca2dbf07ad5a8333 {
+		t.Errorf("Unexpected round constants %x %x", tables2003.rc[1], tables2003.rc[10])
+	}
+	var seen [256]bool
+	for _, s := range sBox0 {
+		seen[s] = true
+	}
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("Whirlpool-0 S-box is not a permutation, missing %02x", i)
+		}
+	}
+}
+
+func assertVariantHash(t *testing.T, v Variant, input, expectedOutput string) {
+	h := NewVariant(v)
+	h.Write([]byte(input))
+	actual := strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+	if expectedOutput != actual {
+		t.Errorf("%v: Expected %v, got %v", v, expectedOutput, actual)
+	}
+}
+
+func TestWhirlpoolNessieSet2(t *testing.T) {
+	// Set 2: messages consisting of n zero bits.
+	vectors := []struct {
+		n      uint64
+		digest string
+	}{
+		{0, "19FA61D75522A4669B44E39C1D2E1726C530232130D407F89AFEE0964997F7A73E83BE698B288FEBCF88E3E03C4F0757EA8964E59B63D93708B138CC42A66EB3"},
+		{1, "E384D540E0BDFD28C8529177343B31183FB40C20F960B0BCDCE0513A382F96A3832099EBB6AABDB71B0EA2E30177F698EA703DE51F93CF3CFEA6D3171B955383"},
+		{7, "FCA3E2F062017253C68ABFC45C05AB761E15B7350AC2AE347FFFFCC1E0AA09ED5AEAAA2D35BB2EB28A8D3710A52E92A62E11ECB4B2698AF32ED35A31FD6C81E9"},
+		{8, "4D9444C212955963D425A410176FCCFB74161E6839692B4C11FDE2ED6EB559EFE0560C39A7B61D5A8BCABD6817A3135AF80F342A4942CCAAE745ABDDFB6AFED0"},
+		{9, "5ABBC45C92838362F6FB4B9B64DA43B68D2BE1706BEDBBD053C1509B83A532BAB0E74F3CD9ACB5DAA56E25B290F8B444FA75AA501EEA4A82ADAADDB08024561A"},
+		{255, "8432020A603DD464CBA39312AADFCD85D5C1197F960D942F6867190D521E5089C0789B0C60361DAFB0984CB287A1DE7BD9E2240CCE1A592CAF8753A23114E869"},
+		{256, "961B5F299F750F880FCA004BDF2882E2FE1B491B0C0EE7E2B514C5DFDD53292DBDBEE17E6D3BB5824CDEC1867CC7090963BE8FFF0C1D8ED5864E07CACB50D68A"},
+		{257, "33A74ADE72B92472447035930455DC111BCD4A2D3C61358695A0D868333025BE2C54121354326083451057944114F99E9AE05FDA919092A78F22C761354AD0FE"},
+		{511, "B9D19DE07ACC38C241D11D8D6FCA817C347875BCA73F5F38CE4E0FF1D3F32D8269666FCD34BBBAC2F24CC63256E6CF9F738A9672A9A8B613C625848CAC4BBE84"},
+		{512, "15CFA7C1DF8E0D6753D9A9AED0642867E26BB3CF11DF7DAC96F60C274E060FDA941EC41EAFF5F7375F3839632516AE9A831D9F2FBE2BD0FF02E9CF16E99EBD03"},
+		{1023, "BA1F1AB4572FED30B77E651B0ECE6FD6C68296E92A8121550B08606FB0DF72C8604D5A593252C27EF985740C27AE43361A439F8E966C3BCF4B757E533E13A4B8"},
+	}
+	zeros := make([]byte, 128)
+	for _, v := range vectors {
+		digest := SumBits(zeros, v.n)
+		assertBitsHash(t, v.n, digest[:], v.digest)
+	}
+}
+
+func TestWhirlpoolNessieSet3(t *testing.T) {
+	// Set 3: 512-bit messages with only bit i set.
+	vectors := []struct {
+		i      uint
+		digest string
+	}{
+		{0, "103E0055A9B090E11C8FDDEBBA06C05ACE8B64B896128F6EED3071FCF3DC16946778E07223233FD180FC40CCDB8430A640E37634271E655CA1674EBFF507F8CB"},
+		{1, "A892E8125F792EE5997D175257633BF889F947759AD6F19DD233F467A3261643F815DED3EED7892A315402CB341FE713C109C0C217A9F4C53BB9920AF88136E7"},
+		{7, "11AFB4234AA6D723BE6A8270FFBD1800478FEF76EEE17CF62E645BD62FB1C702DD32B2D8E9C2FBDEDF3BE1EFD4C8101F768127F0E7391F6600DBE27F0252A024"},
+		{8, "357B42EA79BC9786975A3C4470AAB23E6229797BADBD54365B5496E55D9DD79FE9624FB42266930A628ED4DB08F9DD35EF1BE10453FC18F42C7F5E1F9BAE55E0"},
+		{255, "F3707937699425B7E5BAF51D2D0AE2492BF0F4976E1433C136FEDF2DACB9C66FD3BEAE0548868BD068A00B079E25430087A0C7E159CCD78C2ABE8360681AEE61"},
+		{510, "C9BD9C778669D411EC9F723FA0EC96FFF5C0F82BE52746037BDB9405EB33A8115C00A6BD5F5260730BC2437027609BF45F402FA8C1A26C0FE2C9D4E9255E6C8D"},
+		{511, "F1748C6EC048CB59FD271FA933C1CAD6D00D86D66FCCA9188F1B4239D50E34BBDBB6DFCCAE9BF8E4291A0AD5E76D48770D36824B850CFCBB4012D97F2CE5650F"},
+	}
+	for _, v := range vectors {
+		var message [64]byte
+		message[v.i/8] = 0x80 >> (v.i % 8)
+		var h Hash
+		h.WriteBits(message[:], 512)
+		assertBitsHash(t, 512, h.Sum(nil), v.digest)
+	}
+}
+
+func TestWhirlpoolWriteBitsInterleaved(t *testing.T) {
+	// the bit string 101 || "abc" || 11010
+	var h Hash
+	h.WriteBits([]byte{0xbf}, 3)
+	h.Write([]byte("abc"))
+	h.WriteBits([]byte{0xd7}, 5)
+	assertBitsHash(t, 32, h.Sum(nil), "4C63A28BAE1A10B1685D700F3C2A02473C68B7255BB3AF55540EB3AE92A29F7F9A2CFEA4970F064992A06746D670503D2F254CC5101109050142CD23C292BEDD")
+}
+
+func assertBitsHash(t *testing.T, nbits uint64, digest []byte, expectedOutput string) {
+	actual := strings.ToUpper(hex.EncodeToString(digest))
+	if expectedOutput != actual {
+		t.Errorf("%d bits: Expected %v, got %v", nbits, expectedOutput, actual)
+	}
+}
+
+type recordingTracer struct {
+	initial []Matrix
+	blocks  []Matrix
+	keys    []Matrix
+	states  []Matrix
+	outputs []Matrix
+}
+
+func (rt *recordingTracer) InitialState(h Matrix) { rt.initial = append(rt.initial, h) }
+
+func (rt *recordingTracer) Block(data, key, state Matrix) {
+	rt.blocks = append(rt.blocks, data)
+	rt.keys = append(rt.keys, key)
+	rt.states = append(rt.states, state)
+}
+
+func (rt *recordingTracer) Round(r int, key, state Matrix) {
+	rt.keys = append(rt.keys, key)
+	rt.states = append(rt.states, state)
+}
+
+func (rt *recordingTracer) Output(h Matrix) { rt.outputs = append(rt.outputs, h) }
+
+func TestWhirlpoolTracer(t *testing.T) {
+	rt := new(recordingTracer)
+	h := NewVariant(Whirlpool, WithTracer(rt))
+	h.Write([]byte("abc"))
+	digest := h.Sum(nil)
+
+	if len(rt.initial) != 1 || rt.initial[0] != (Matrix{}) {
+		t.Fatalf("Expected a single zero initial state, got %v", rt.initial)
+	}
+	if len(rt.blocks) != 1 || len(rt.keys) != cRounds+1 || len(rt.outputs) != 1 {
+		t.Fatalf("Expected one block of %d rounds, got %d blocks, %d keys, %d outputs",
+			cRounds, len(rt.blocks), len(rt.keys), len(rt.outputs))
+	}
+	if rt.blocks[0].Row(0) != [8]byte{'a', 'b', 'c', 0x80} {
+		t.Errorf("Unexpected first row of Z' %x", rt.blocks[0].Row(0))
+	}
+	// intermediate values of the first and last round for "abc"
+	if rt.keys[1][0] != 0x300beec0af902967 || rt.keys[1][1] != 0x2828282828282828 {
+		t.Errorf("Unexpected K_1 %016x", rt.keys[1])
+	}
+	if rt.states[cRounds][0] != 0x2f462b24c6f486bb {
+		t.Errorf("Unexpected W' %016x", rt.states[cRounds])
+	}
+	var output []byte
+	for i := range rt.outputs[0] {
+		row := rt.outputs[0].Row(i)
+		output = append(output, row[:]...)
+	}
+	if !bytes.Equal(output, digest) {
+		t.Errorf("Expected traced output %x to equal digest %x", output, digest)
+	}
+}
+
+func TestWhirlpoolTextTracer(t *testing.T) {
+	var trace bytes.Buffer
+	h := NewVariant(Whirlpool, WithTracer(NewTextTracer(&trace)))
+	h.Write([]byte("abc"))
+	digest := h.Sum(nil)
+
+	text := trace.String()
+	for _, expected := range []string{
+		"Initial hash value:" + LB,
+		"    61 62 63 80 00 00 00 00" + LB,
+		"i = 1:" + LB + "    30 0B EE C0 AF 90 29 67        ",
+		"i = 10:" + LB,
+		"The value of Y' output from the round-function is as follows." + LB +
+			fmt.Sprintf("    % X", digest[:8]) + LB,
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected trace to contain %q", expected)
+		}
+	}
+}
+
+func TestWhirlpoolCipherCompression(t *testing.T) {
+	// With the zero IV as key, W encrypts the single padded block of
+	// "abc" to the digest XOR the block (Miyaguchi-Preneel).
+	block := make([]byte, CipherBlockSize)
+	copy(block, "abc\x80")
+	block[CipherBlockSize-1] = 24
+
+	c, err := NewCipher(make([]byte, CipherKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, CipherBlockSize)
+	c.Encrypt(out, block)
+	for i := range out {
+		out[i] ^= block[i]
+	}
+	digest := Sum512([]byte("abc"))
+	if !bytes.Equal(out, digest[:]) {
+		t.Errorf("Expected %x, got %x", digest, out)
+	}
+}
+
+func TestWhirlpoolCipherRoundTrip(t *testing.T) {
+	key := make([]byte, CipherKeySize)
+	plaintext := make([]byte, CipherBlockSize)
+	for i := range key {
+		key[i] = byte(7*i + 1)
+		plaintext[i] = byte(i * i)
+	}
+	for _, v := range []Variant{Whirlpool, WhirlpoolT, Whirlpool0} {
+		c, err := NewVariantCipher(v, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.BlockSize() != CipherBlockSize {
+			t.Errorf("Expected block size %d, got %d", CipherBlockSize, c.BlockSize())
+		}
+		ciphertext := make([]byte, CipherBlockSize)
+		c.Encrypt(ciphertext, plaintext)
+		if bytes.Equal(ciphertext, plaintext) {
+			t.Errorf("%v: Encrypt did not change the block", v)
+		}
+		decrypted := make([]byte, CipherBlockSize)
+		c.Decrypt(decrypted, ciphertext)
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("%v: Expected %x, got %x", v, plaintext, decrypted)
+		}
+	}
+}
+
+func TestWhirlpoolCipherKeySize(t *testing.T) {
+	for _, n := range []int{0, 32, 63, 65} {
+		if _, err := NewCipher(make([]byte, n)); err != KeySizeError(n) {
+			t.Errorf("Expected KeySizeError(%d), got %v", n, err)
+		}
+	}
+}
+
+func TestWhirlpoolHMAC(t *testing.T) {
+	// the HMAC-Whirlpool examples of ISO/IEC 9797-2 are not included:
+	// the standard is sold by ISO and its annex could not be consulted,
+	// so its values cannot be quoted or checked here. These use the keys
+	// and messages of the HMAC-RIPEMD-160 examples of the RIPEMD-160 page
+	// (A. Bosselaers), plus a key longer than a block, and were checked
+	// with OpenSSL 3.0: openssl mac -digest whirlpool -macopt hexkey:KEY HMAC
+	key1, _ := hex.DecodeString("00112233445566778899aabbccddeeff01234567")
+	key2, _ := hex.DecodeString("0123456789abcdeffedcba987654321000112233")
+	longKey := make([]byte, 100) // longer than a block, hashed first
+	for i := range longKey {
+		longKey[i] = byte(i)
+	}
+	vectors := []struct {
+		key     []byte
+		message string
+		mac     string
+	}{
+		{key1, "", "16e471590107306250f7f5aeeaecd20e7341ad8492b51e39d0deb3b949bdb2cd1d253b61e22400d630dbcfb66f0a5af3b5fc05522821c8a5279afc597c42d90b"},
+		{key1, "a", "14cd747f3f1c4f1e8b2174001f3990ae4022e1940459582eb6f83b1b5920b423a6e10d6adeed0f646289f4618dff06fc482087bf33606ce51ea88348e90acbfe"},
+		{key1, "abc", "1483adf818626f024d8e1651bc0308c4afa8e3bd848fba8a299d8a462c22ccb4b3673b5b448370612c8d10e62ec965b07556d1cd00b2044553a621e01be58661"},
+		{key1, strings.Repeat("1234567890", 8), "c55e91dd08fc90096a6aae23e0d8ac54e2ae91a9f3ad65725a5546dea9512587aa193bc745592a55b0a29cd25a813832a62551b9913263f45222ca17efe61da6"},
+		{key2, "", "6d134b95cb3232916a3303a7a70dc84be49d5f191829a595a5457aebe2b185d82f282561e68527cc8133fd2faacf6ed40e412b53f8dbc7a1d65dbbb83320f186"},
+		{key2, "abc", "f279ef24040b9d453e07a03cc6f05b017a7e46716e7a312049b8189c43486b0fd48a7d520f709d7c8fef9670395de7ac096d8f0e274bdec6562b11f4f0ded179"},
+		{key2, strings.Repeat("1234567890", 8), "64545ed921f8c75d09618a0ced7a3b603b594b293bdd833410fe5222a7ad84d72dccf50fbe5ec746a3b682b3bf759cc1b2cc3848d835822c31b6936e670a3a29"},
+		{longKey, "abc", "7b348cefd1e08aaf0644e9b4fde6a3cdad517f1d3157cb9b925dd25d67620061f385e4ad5b883408ea44d003b38e4e13d11d8ad9b57d72a570bf697f4cf2749d"},
+	}
+	for _, v := range vectors {
+		mac := MAC(v.key, []byte(v.message))
+		if actual := hex.EncodeToString(mac); actual != v.mac {
+			t.Errorf("MAC(%x, %q): Expected %v, got %v", v.key, v.message, v.mac, actual)
+		}
+		if !ValidMAC(v.key, []byte(v.message), mac) {
+			t.Errorf("Expected ValidMAC to accept its own MAC")
+		}
+		mac[0] ^= 1
+		if ValidMAC(v.key, []byte(v.message), mac) {
+			t.Errorf("Expected ValidMAC to reject a modified MAC")
+		}
+	}
+}
+
+func TestWhirlpoolSalted(t *testing.T) {
+	h := NewSalted([]byte("salt"))
+	h.Write([]byte("mess"))
+	h.Write([]byte("age"))
+	expected := "5f9c2fd7f8c8974c21b6a3996290200c0950f8f20d2a2251843cbcfa9328ea96744f5d40ea18683768c655646381eb49e11c9f801d30470a6920faa46d62c386"
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+	h.Reset()
+	h.Write([]byte("message"))
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		t.Errorf("Expected %v after Reset, got %v", expected, actual)
+	}
+
+	// the salt/message boundary is not ambiguous
+	a, b := NewSalted([]byte("ab")), NewSalted([]byte("a"))
+	a.Write([]byte("c"))
+	b.Write([]byte("bc"))
+	if bytes.Equal(a.Sum(nil), b.Sum(nil)) {
+		t.Errorf("Expected different digests for different salts")
+	}
+
+	data := make([]byte, 1<<20)
+	if allocs := testing.AllocsPerRun(10, func() { h.Write(data) }); allocs != 0 {
+		t.Errorf("Expected Write not to allocate, got %v allocations", allocs)
+	}
+}
+
+func TestHashOfBytes(t *testing.T) {
+	expected := Sum512([]byte("saltmessage"))
+	if actual := HashOfBytes([]byte("message"), []byte("salt")); !bytes.Equal(actual, expected[:]) {
+		t.Errorf("Expected %x, got %x", expected, actual)
+	}
+	if actual := HashOfString("message", []byte("salt")); !bytes.Equal(actual, expected[:]) {
+		t.Errorf("Expected %x, got %x", expected, actual)
+	}
+}
+
+func TestWhirlpoolMarshalBinary(t *testing.T) {
+	message := []byte(strings.Repeat("abcdefghijklmnopqrstuvwxyz", 10))
+	for _, v := range []Variant{Whirlpool, WhirlpoolT, Whirlpool0} {
+		expected := NewVariant(v)
+		expected.Write(message)
+
+		for _, split := range []int{0, 1, 31, 32, 63, 64, 65, 200, len(message)} {
+			h := NewVariant(v)
+			h.Write(message[:split])
+			state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.Write([]byte("garbage"))
+
+			resumed := NewVariant(v)
+			if err := resumed.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+				t.Fatal(err)
+			}
+			resumed.Write(message[split:])
+			if !bytes.Equal(resumed.Sum(nil), expected.Sum(nil)) {
+				t.Errorf("%v split at %d: Expected %x, got %x", v, split, expected.Sum(nil), resumed.Sum(nil))
+			}
+		}
+	}
+}
+
+func TestWhirlpoolMarshalBinaryBits(t *testing.T) {
+	var h, resumed Hash
+	h.WriteBits([]byte{0xbf}, 3)
+	h.Write([]byte("ab"))
+	state, _ := h.MarshalBinary()
+	if err := resumed.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	resumed.Write([]byte("c"))
+	resumed.WriteBits([]byte{0xd7}, 5)
+	assertBitsHash(t, 32, resumed.Sum(nil), "4C63A28BAE1A10B1685D700F3C2A02473C68B7255BB3AF55540EB3AE92A29F7F9A2CFEA4970F064992A06746D670503D2F254CC5101109050142CD23C292BEDD")
+}
+
+func TestWhirlpoolMarshalBinaryEqualStates(t *testing.T) {
+	// h fills its buffer before hashing it, which leaves stale bytes past
+	// the end of the buffer; fresh hashes both blocks straight from the
+	// message and never touches its buffer.
+	message := bytes.Repeat([]byte{0xaa}, 2*BlockSize)
+	var h, fresh Hash
+	h.Write(message[:BlockSize+40])
+	h.Write(message[BlockSize+40:])
+	fresh.Write(message)
+	for _, s := range []*Hash{&h, &fresh} {
+		s.Write([]byte("abc"))
+		s.WriteBits([]byte{0xe0}, 3)
+	}
+
+	state, _ := h.MarshalBinary()
+	expected, _ := fresh.MarshalBinary()
+	if !bytes.Equal(state, expected) {
+		t.Errorf("Expected %x, got %x", expected, state)
+	}
+}
+
+func TestWhirlpoolUnmarshalBinaryErrors(t *testing.T) {
+	var h Hash
+	state, _ := h.MarshalBinary()
+	if err := h.UnmarshalBinary(state[:len(state)-1]); err == nil {
+		t.Errorf("Expected an error for a truncated state")
+	}
+	if err := NewVariant(Whirlpool0).(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err == nil {
+		t.Errorf("Expected an error for a state of another variant")
+	}
+	for _, other := range []hash.Hash{New384(), New256()} {
+		if err := other.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err == nil {
+			t.Errorf("Expected an error for a state of another size, got none for %d bytes", other.Size())
+		}
+	}
+	state[len(magic)+2+cDigestBytes+cWBlockBytes] = 0xff
+	if err := h.UnmarshalBinary(state); err == nil {
+		t.Errorf("Expected an error for an invalid buffer length")
+	}
+	state[0] ^= 1
+	if err := h.UnmarshalBinary(state); err == nil {
+		t.Errorf("Expected an error for a state with an invalid identifier")
+	}
+}
+
+func TestWhirlpoolNewFromDigest(t *testing.T) {
+	secret, message, suffix := []byte("secret key"), []byte("user=guest"), []byte(";admin=true")
+	mac := Sum512(append(append([]byte(nil), secret...), message...))
+
+	forger, glue, err := NewFromDigest(mac[:], uint64(len(secret)+len(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(secret) + len(message) + len(glue); l%BlockSize != 0 {
+		t.Errorf("Expected the glue to end on a block boundary, got %d bytes", l)
+	}
+	forger.Write(suffix)
+
+	var expected Hash
+	expected.Write(secret)
+	expected.Write(message)
+	expected.Write(glue)
+	expected.Write(suffix)
+	if !bytes.Equal(forger.Sum(nil), expected.Sum(nil)) {
+		t.Errorf("Expected forged digest %x, got %x", expected.Sum(nil), forger.Sum(nil))
+	}
+	if _, _, err := NewFromDigest(mac[:Size-1], 0); err == nil {
+		t.Errorf("Expected an error for a short digest")
+	}
+}
+
+func TestWhirlpoolTruncated(t *testing.T) {
+	// crypto/testmgr.h wp384 and wp256 vectors of the Linux kernel
+	vectors := []struct {
+		input, wp384, wp256 string
+	}{
+		{"", "19fa61d75522a4669b44e39c1d2e1726c530232130d407f89afee0964997f7a73e83be698b288febcf88e3e03c4f0757", "19fa61d75522a4669b44e39c1d2e1726c530232130d407f89afee0964997f7a7"},
+		{"a", "8aca2602792aec6f11a67206531fb7d7f0dff59413145e6973c45001d0087b42d11bc645413aeff63a42391a39145a59", "8aca2602792aec6f11a67206531fb7d7f0dff59413145e6973c45001d0087b42"},
+		{"abc", "4e2448a4c6f486bb16b6562c73b4020bf3043e3a731bce721ae1b303d97e6d4c7181eebdb6c57e277d0e34957114cbd6", "4e2448a4c6f486bb16b6562c73b4020bf3043e3a731bce721ae1b303d97e6d4c"},
+		{"message digest", "378c84a4126e2dc6e56dcc7458377aac838d00032230f53ce1f5700c0ffb4d3b8421557659ef55c106b4b52ac5a4aaa6", "378c84a4126e2dc6e56dcc7458377aac838d00032230f53ce1f5700c0ffb4d3b"},
+		{"abcdefghijklmnopqrstuvwxyz", "f1d754662636ffe92c82ebb9212a484a8d38631ead4238f5442ee13b8054e41b08bf2a9251c30b6a0b8aae86177ab4a6", "f1d754662636ffe92c82ebb9212a484a8d38631ead4238f5442ee13b8054e41b"},
+		{"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789", "dc37e008cf9ee69bf11f00ed9aba26901dd7c28cdec066cc6af42e40f82f3a1e08eba26629129d8fb7cb57211b9281a6", "dc37e008cf9ee69bf11f00ed9aba26901dd7c28cdec066cc6af42e40f82f3a1e"},
+		{strings.Repeat("1234567890", 8), "466ef18babb0154d25b9d38a6414f5c08784372bccb204d6549c4afadb6014294d5bd8df2a6c44e538cd047b2681a51a", "466ef18babb0154d25b9d38a6414f5c08784372bccb204d6549c4afadb601429"},
+		{"abcdbcdecdefdefgefghfghighijhijk", "2a987ea40f917061f5d6f0a0e4644f488a7a5a52deee656207c562f988e95c6916bdc8031bc5be1b7b947639fe050b56", "2a987ea40f917061f5d6f0a0e4644f488a7a5a52deee656207c562f988e95c69"},
+	}
+	for _, v := range vectors {
+		wp384, wp256 := New384(), New256()
+		wp384.Write([]byte(v.input))
+		wp256.Write([]byte(v.input))
+		sum384, sum256 := Sum384([]byte(v.input)), Sum256([]byte(v.input))
+		for _, actual := range []string{hex.EncodeToString(wp384.Sum(nil)), hex.EncodeToString(sum384[:])} {
+			if actual != v.wp384 {
+				t.Errorf("wp384(%q): Expected %v, got %v", v.input, v.wp384, actual)
+			}
+		}
+		for _, actual := range []string{hex.EncodeToString(wp256.Sum(nil)), hex.EncodeToString(sum256[:])} {
+			if actual != v.wp256 {
+				t.Errorf("wp256(%q): Expected %v, got %v", v.input, v.wp256, actual)
+			}
+		}
+	}
+
+	h := New384()
+	h.Write([]byte("abc"))
+	h.Reset()
+	if h.Size() != Size384 || New256().Size() != Size256 || len(h.Sum(nil)) != Size384 {
+		t.Errorf("Expected truncated sizes to survive Reset")
+	}
+}
+
+func TestWhirlpoolCompressionFunctions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, 16*BlockSize)
+	rng.Read(data)
+	for _, v := range []Variant{Whirlpool, WhirlpoolT, Whirlpool0} {
+		tab := v.tables()
+		var h0 [8]uint64
+		for i := range h0 {
+			h0[i] = rng.Uint64()
+		}
+		expected := h0
+		blockGeneric(&expected, tab, data)
+		actual := h0
+		block(&actual, tab, data)
+		if actual != expected {
+			t.Errorf("%v block: Expected %x, got %x", v, expected, actual)
+		}
+		actual = h0
+		blockCompact(&actual, tab, data)
+		if actual != expected {
+			t.Errorf("%v blockCompact: Expected %x, got %x", v, expected, actual)
+		}
+		traced := &Hash{hash: h0, tracer: &recordingTracer{}}
+		traced.blockTraced(tab, data)
+		if traced.hash != expected {
+			t.Errorf("%v blockTraced: Expected %x, got %x", v, expected, traced.hash)
+		}
+	}
+}
+
+func TestWhirlpoolCompactTables(t *testing.T) {
+	data := make([]byte, 1000)
+	rand.New(rand.NewSource(2)).Read(data)
+	for _, v := range []Variant{Whirlpool, WhirlpoolT, Whirlpool0} {
+		expected := NewVariant(v)
+		expected.Write(data)
+		actual := NewVariant(v, WithCompactTables())
+		actual.Write(data)
+		if !bytes.Equal(actual.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("%v: Expected %x, got %x", v, expected.Sum(nil), actual.Sum(nil))
+		}
+		actual.Reset()
+		if !actual.(*Hash).compact {
+			t.Errorf("%v: Expected compact tables to survive Reset", v)
+		}
+	}
+}
+
+func TestWhirlpoolWriteSplits(t *testing.T) {
+	data := make([]byte, 3*BlockSize+7)
+	rand.New(rand.NewSource(3)).Read(data)
+	expected := Sum512(data)
+	for i := 0; i <= len(data); i++ {
+		for _, j := range []int{i, (i + len(data)) / 2, len(data)} {
+			h := New()
+			h.Write(data[:i])
+			h.Write(data[i:j])
+			h.Write(data[j:])
+			if actual := h.Sum(nil); !bytes.Equal(actual, expected[:]) {
+				t.Fatalf("split %d/%d: Expected %x, got %x", i, j, expected, actual)
+			}
+		}
+	}
+}
+
+func TestWhirlpoolAllocations(t *testing.T) {
+	data := make([]byte, 1000)
+	h := New()
+	out := make([]byte, 0, Size)
+	n := testing.AllocsPerRun(10, func() {
+		h.Reset()
+		h.Write(data)
+		out = h.Sum(out[:0])
+		Sum512(data)
+	})
+	if n > 0 {
+		t.Errorf("Expected 0 allocations, got %v", n)
+	}
+}
+
+var buf = make([]byte, 1<<20)
+
+func benchmarkSize(b *testing.B, h hash.Hash, size int) {
+	sum := make([]byte, 0, Size)
+	b.SetBytes(int64(size))
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		h.Write(buf[:size])
+		h.Sum(sum[:0])
+	}
+}
+
+func BenchmarkHash64(b *testing.B)  { benchmarkSize(b, New(), 64) }
+func BenchmarkHash1K(b *testing.B)  { benchmarkSize(b, New(), 1024) }
+func BenchmarkHash8K(b *testing.B)  { benchmarkSize(b, New(), 8192) }
+func BenchmarkHash64K(b *testing.B) { benchmarkSize(b, New(), 64<<10) }
+func BenchmarkHash1M(b *testing.B)  { benchmarkSize(b, New(), 1<<20) }
+
+func BenchmarkHashCompact64(b *testing.B) {
+	benchmarkSize(b, NewVariant(Whirlpool, WithCompactTables()), 64)
+}
+
+func BenchmarkHashCompact8K(b *testing.B) {
+	benchmarkSize(b, NewVariant(Whirlpool, WithCompactTables()), 8192)
+}
+
+func BenchmarkBlockGeneric8K(b *testing.B) {
+	var h [8]uint64
+	b.SetBytes(8192)
+	for i := 0; i < b.N; i++ {
+		blockGeneric(&h, tables2003, buf[:8192])
+	}
+}
+
+func BenchmarkSum512(b *testing.B) {
+	b.SetBytes(8192)
+	for i := 0; i < b.N; i++ {
+		Sum512(buf[:8192])
+	}
+}
+
+func TestBitslicedSBox(t *testing.T) {
+	for _, v := range []Variant{Whirlpool, Whirlpool0} {
+		bs := v.bitsliced()
+		// byte x of the planes is x, x+64, x+128 and x+192 in turn.
+		for base := 0; base < 256; base += 64 {
+			var rows, planes [8]uint64
+			for i := 0; i < 64; i++ {
+				rows[i/8] |= uint64(base+i) << (56 - 8*(i%8))
+			}
+			toPlanes(&planes, &rows)
+			bs.subBytes(&planes)
+			fromPlanes(&rows, &planes)
+			for i := 0; i < 64; i++ {
+				x := base + i
+				expected := byte(v.tables().c[0][x] >> 56)
+				actual := byte(rows[i/8] >> (56 - 8*(i%8)))
+				if expected != actual {
+					t.Errorf("%v S(%02x): Expected %02x, got %02x", v, x, expected, actual)
+				}
+			}
+		}
+	}
+}
+
+func TestBitslicedRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for _, v := range []Variant{Whirlpool, WhirlpoolT, Whirlpool0} {
+		for n := 0; n < 20; n++ {
+			var h0, planes, rows [8]uint64
+			for i := range h0 {
+				h0[i] = rng.Uint64()
+			}
+			toPlanes(&planes, &h0)
+			if fromPlanes(&rows, &planes); rows != h0 {
+				t.Fatalf("Expected planes to round-trip %x, got %x", h0, rows)
+			}
+			data := make([]byte, BlockSize*(1+rng.Intn(4)))
+			rng.Read(data)
+			expected, actual := h0, h0
+			blockGeneric(&expected, v.tables(), data)
+			blockBitsliced(&actual, v.bitsliced(), data)
+			if actual != expected {
+				t.Errorf("%v: Expected %x, got %x", v, expected, actual)
+			}
+		}
+		data := make([]byte, rng.Intn(1000))
+		rng.Read(data)
+		expected := NewVariant(v)
+		expected.Write(data)
+		actual := NewVariant(v, WithConstantTime())
+		actual.Write(data)
+		if !bytes.Equal(actual.Sum(nil), expected.Sum(nil)) {
+			t.Errorf("%v: Expected %x, got %x", v, expected.Sum(nil), actual.Sum(nil))
+		}
+	}
+}
+
+func TestWhirlpoolConstantTimeTracer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic for WithTracer and WithConstantTime")
+		}
+	}()
+	NewVariant(Whirlpool, WithConstantTime(), WithTracer(new(recordingTracer)))
+}
+
+func BenchmarkHashConstantTime8K(b *testing.B) {
+	benchmarkSize(b, NewVariant(Whirlpool, WithConstantTime()), 8192)
+}
+
+func TestConformance(t *testing.T) {
+	hashtest.TestHash(t, New)
+	t.Run("wp384", func(t *testing.T) { hashtest.TestHash(t, New384) })
+	t.Run("wp256", func(t *testing.T) { hashtest.TestHash(t, New256) })
+	for _, v := range []Variant{WhirlpoolT, Whirlpool0} {
+		t.Run(v.String(), func(t *testing.T) {
+			hashtest.TestHash(t, func() hash.Hash { return NewVariant(v) })
+		})
+	}
+	t.Run("compact", func(t *testing.T) {
+		hashtest.TestHash(t, func() hash.Hash { return NewVariant(Whirlpool, WithCompactTables()) })
+	})
+	t.Run("constant-time", func(t *testing.T) {
+		hashtest.TestHash(t, func() hash.Hash { return NewVariant(Whirlpool, WithConstantTime()) })
+	})
+	t.Run("salted", func(t *testing.T) {
+		hashtest.TestHash(t, func() hash.Hash { return NewSalted([]byte("salt")) })
+	})
+}
+
+func TestNESSIE(t *testing.T) {
+	// the header of the file says where its vectors come from.
+	hashtest.TestNESSIE(t, New, "testdata/nessie.txt")
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := selftest.RunAll(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	saved := selfTest
+	defer func() { selfTest = saved }()
+	selfTest = selftest.New("broken Whirlpool", func() error { return errors.New("broken") })
+	if _, err := NewCipher(make([]byte, CipherKeySize)); err == nil {
+		t.Errorf("Expected NewCipher to fail")
+	}
+	if _, _, err := NewFromDigest(make([]byte, Size), 0); err == nil {
+		t.Errorf("Expected NewFromDigest to fail")
+	}
+	for name, construct := range map[string]func(){
+		"New":     func() { New() },
+		"New384":  func() { New384() },
+		"NewHMAC": func() { NewHMAC(nil) },
+		"Sum512":  func() { Sum512(nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected %s to panic", name)
+				}
+			}()
+			construct()
+		}()
+	}
+}
@@ -0,0 +1,101 @@
+package whirlpool
+
+//  Tracing of the intermediate values of the Whirlpool compression
+//  function, in the layout of the ``Intermediate values'' document
+//  distributed with the reference implementation.
+import (
+	"fmt"
+	"io"
+)
+
+// Matrix is an 8x8 byte matrix of the Whirlpool state, one row per
+// word with the first column in the most significant byte.
+type Matrix [8]uint64
+
+// Row returns the bytes of row i.
+func (m Matrix) Row(i int) [8]byte {
+	var row [8]byte
+	for j := range row {
+		row[j] = byte(m[i] >> uint(56-8*j))
+	}
+	return row
+}
+
+// Tracer receives the intermediate values of a Hash as they are
+// computed. Digests finalized by Sum are traced as well, including
+// the padding blocks.
+type Tracer interface {
+	// InitialState is called with the initial hash value, when the
+	// Hash is created and on every Reset.
+	InitialState(h Matrix)
+	// Block is called at the start of every compression with the data
+	// block Z', the key K^0 (the chaining value) and the cipher state
+	// after the initial key addition.
+	Block(data, key, state Matrix)
+	// Round is called after round r, 1 <= r <= 10, with the round key
+	// K^r and the cipher state.
+	Round(r int, key, state Matrix)
+	// Output is called with the chaining value after the
+	// Miyaguchi-Preneel feed-forward.
+	Output(h Matrix)
+}
+
+// NewTextTracer returns a Tracer that writes the intermediate values
+// to w in the format of the official test vector document, with LB
+// line breaks, so that traces can be compared with other
+// implementations using diff. Write errors are ignored.
+func NewTextTracer(w io.Writer) Tracer {
+	return &textTracer{w: w}
+}
+
+type textTracer struct {
+	w io.Writer
+}
+
+func (tt *textTracer) InitialState(h Matrix) {
+	fmt.Fprint(tt.w, "Initial hash value:"+LB)
+	tt.matrices(h)
+	fmt.Fprint(tt.w, LB)
+}
+
+func (tt *textTracer) Block(data, key, state Matrix) {
+	fmt.Fprint(tt.w, "The 8x8 matrix Z' derived from the"+
+		" data-string is as follows."+LB)
+	tt.matrices(data)
+	fmt.Fprint(tt.w, LB)
+	fmt.Fprint(tt.w, "The K_0 matrix (from the initialization value IV)"+
+		" and X'' matrix are as follows."+LB)
+	tt.matrices(key, state)
+	fmt.Fprint(tt.w, LB+
+		"The following are (hexadecimal representations of) the"+
+		" successive values of the variables"+
+		" K_i for i = 1 to 10 and W'."+LB+LB)
+}
+
+func (tt *textTracer) Round(r int, key, state Matrix) {
+	fmt.Fprintf(tt.w, "i = %d:"+LB, r)
+	tt.matrices(key, state)
+	fmt.Fprint(tt.w, LB)
+}
+
+func (tt *textTracer) Output(h Matrix) {
+	fmt.Fprint(tt.w, "The value of Y' output from the"+
+		" round-function is as follows."+LB)
+	tt.matrices(h)
+	fmt.Fprint(tt.w, LB)
+}
+
+// matrices prints the given matrices side by side.
+func (tt *textTracer) matrices(ms ...Matrix) {
+	for i := 0; i < 8; i++ {
+		for k, m := range ms {
+			if k > 0 {
+				fmt.Fprint(tt.w, "    ")
+			}
+			row := m.Row(i)
+			fmt.Fprintf(tt.w, "    %02X %02X %02X %02X %02X %02X %02X %02X",
+				row[0], row[1], row[2], row[3], row[4], row[5], row[6], row[7])
+		}
+		fmt.Fprint(tt.w, LB)
+	}
+}
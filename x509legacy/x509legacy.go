@@ -0,0 +1,384 @@
+// Package x509legacy verifies X.509 certificate chains signed with
+// algorithms that crypto/x509 refuses or does not know, such as
+// md2WithRSAEncryption, md5WithRSAEncryption, sha1WithRSAEncryption,
+// RIPEMD-160 with RSA and DSA with SHA-1, for validating archived
+// document signing chains.
+//
+// Certificates are parsed with crypto/x509; the signatures are checked
+// here, hashing the TBSCertificate with the hashes of this module or of
+// the standard library. A valid chain comes with the list of the
+// signatures made with a legacy algorithm, which callers should show
+// rather than treat as an error.
+package x509legacy
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/dsa"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/y3sh/go-legacy-crypto/legacy"
+	"github.com/y3sh/go-legacy-crypto/pkcs1"
+)
+
+// An Algorithm describes a signature algorithm.
+type Algorithm struct {
+	Name      string
+	OID       asn1.ObjectIdentifier
+	PublicKey x509.PublicKeyAlgorithm // x509.RSA or x509.DSA
+	Hash      *legacy.Hash
+
+	// Legacy algorithms are reported in the warnings of a valid chain.
+	Legacy bool
+}
+
+var (
+	// ErrUnknownAlgorithm is returned, wrapped, for signatures made with
+	// an algorithm that is not registered.
+	ErrUnknownAlgorithm = errors.New("x509legacy: unknown signature algorithm")
+
+	// ErrSignature is returned, wrapped, for signatures that do not
+	// verify.
+	ErrSignature = errors.New("x509legacy: invalid signature")
+
+	// ErrNoChain is returned, wrapped, when no chain leads from the
+	// certificate to a root.
+	ErrNoChain = errors.New("x509legacy: no valid chain to a root")
+)
+
+// Digest algorithms of the standard library, described for pkcs1.
+var (
+	hashMD5    = stdHash("MD5", md5.New, 1, 2, 840, 113549, 2, 5)
+	hashSHA1   = stdHash("SHA-1", sha1.New, 1, 3, 14, 3, 2, 26)
+	hashSHA256 = stdHash("SHA-256", sha256.New, 2, 16, 840, 1, 101, 3, 4, 2, 1)
+	hashSHA384 = stdHash("SHA-384", sha512.New384, 2, 16, 840, 1, 101, 3, 4, 2, 2)
+	hashSHA512 = stdHash("SHA-512", sha512.New, 2, 16, 840, 1, 101, 3, 4, 2, 3)
+)
+
+func stdHash(name string, newHash func() hash.Hash, oid ...int) *legacy.Hash {
+	h := newHash()
+	return &legacy.Hash{
+		Name:      name,
+		OIDs:      []asn1.ObjectIdentifier{oid},
+		Size:      h.Size(),
+		BlockSize: h.BlockSize(),
+		New:       newHash,
+	}
+}
+
+func mustLookup(name string) *legacy.Hash {
+	h, err := legacy.Lookup(name)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+var (
+	mu          sync.RWMutex
+	byOID       = map[string]*Algorithm{}
+	defaultAlgs = []*Algorithm{
+		{"md2WithRSAEncryption", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 2}, x509.RSA, mustLookup("MD2"), true},
+		{"md5WithRSAEncryption", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 4}, x509.RSA, hashMD5, true},
+		{"sha1WithRSAEncryption", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}, x509.RSA, hashSHA1, true},
+		{"sha1WithRSASignature", asn1.ObjectIdentifier{1, 3, 14, 3, 2, 29}, x509.RSA, hashSHA1, true},
+		{"sha256WithRSAEncryption", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, x509.RSA, hashSHA256, false},
+		{"sha384WithRSAEncryption", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, x509.RSA, hashSHA384, false},
+		{"sha512WithRSAEncryption", asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, x509.RSA, hashSHA512, false},
+		{"rsaSignatureWithripemd160", asn1.ObjectIdentifier{1, 3, 36, 3, 3, 1, 2}, x509.RSA, mustLookup("RIPEMD-160"), true},
+		{"dsa-with-sha1", asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 3}, x509.DSA, hashSHA1, true},
+		{"dsaWithSHA1", asn1.ObjectIdentifier{1, 3, 14, 3, 2, 27}, x509.DSA, hashSHA1, true},
+	}
+)
+
+func init() {
+	for _, a := range defaultAlgs {
+		if err := RegisterAlgorithm(a); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterAlgorithm adds a signature algorithm, typically one without a
+// standard object identifier such as Whirlpool with RSA, under the
+// identifier used by the certificates to verify. It fails if the object
+// identifier is already taken.
+func RegisterAlgorithm(a *Algorithm) error {
+	if a.Name == "" || len(a.OID) == 0 || a.Hash == nil {
+		return fmt.Errorf("x509legacy: incomplete description of %q", a.Name)
+	}
+	if a.PublicKey != x509.RSA && a.PublicKey != x509.DSA {
+		return fmt.Errorf("x509legacy: %s: unsupported public key algorithm %v", a.Name, a.PublicKey)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := byOID[a.OID.String()]; ok {
+		return fmt.Errorf("x509legacy: object identifier %v already registered", a.OID)
+	}
+	byOID[a.OID.String()] = a
+	return nil
+}
+
+// UnregisterAlgorithm removes a, added with RegisterAlgorithm, from the
+// registry. It does nothing if a is not registered.
+func UnregisterAlgorithm(a *Algorithm) {
+	mu.Lock()
+	defer mu.Unlock()
+	if byOID[a.OID.String()] == a {
+		delete(byOID, a.OID.String())
+	}
+}
+
+// LookupAlgorithm returns the signature algorithm with the given object
+// identifier.
+func LookupAlgorithm(oid asn1.ObjectIdentifier) (*Algorithm, error) {
+	mu.RLock()
+	a, ok := byOID[oid.String()]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %v", ErrUnknownAlgorithm, oid)
+	}
+	return a, nil
+}
+
+// certificate is the outer structure of a certificate, from which
+// crypto/x509 does not keep unknown signature algorithm identifiers.
+type certificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+// SignatureAlgorithm returns the algorithm cert is signed with.
+func SignatureAlgorithm(cert *x509.Certificate) (*Algorithm, error) {
+	var c certificate
+	if _, err := asn1.Unmarshal(cert.Raw, &c); err != nil {
+		return nil, fmt.Errorf("x509legacy: malformed certificate: %v", err)
+	}
+	return LookupAlgorithm(c.SignatureAlgorithm.Algorithm)
+}
+
+// CheckSignature checks that sig is a signature of signed with alg by
+// the owner of pub.
+func CheckSignature(alg *Algorithm, pub crypto.PublicKey, signed, sig []byte) error {
+	h := alg.Hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+	switch alg.PublicKey {
+	case x509.RSA:
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("x509legacy: %s signature with a %T key", alg.Name, pub)
+		}
+		if err := pkcs1.Verify(key, alg.Hash, digest, sig); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrSignature, alg.Name, err)
+		}
+	case x509.DSA:
+		key, ok := pub.(*dsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("x509legacy: %s signature with a %T key", alg.Name, pub)
+		}
+		var rs struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) != 0 {
+			return fmt.Errorf("%w: %s: malformed signature", ErrSignature, alg.Name)
+		}
+		if rs.R.Sign() <= 0 || rs.S.Sign() <= 0 || !dsa.Verify(key, digest, rs.R, rs.S) {
+			return fmt.Errorf("%w: %s", ErrSignature, alg.Name)
+		}
+	default:
+		return fmt.Errorf("x509legacy: %s: unsupported public key algorithm %v", alg.Name, alg.PublicKey)
+	}
+	return nil
+}
+
+// CheckSignatureFrom checks that cert is signed by the key of issuer
+// and returns the algorithm of the signature. Algorithms that are not
+// registered are left to crypto/x509 and come back as nil.
+func CheckSignatureFrom(cert, issuer *x509.Certificate) (*Algorithm, error) {
+	alg, err := SignatureAlgorithm(cert)
+	if errors.Is(err, ErrUnknownAlgorithm) && cert.SignatureAlgorithm != x509.UnknownSignatureAlgorithm {
+		if err := issuer.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSignature, err)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return alg, CheckSignature(alg, issuer.PublicKey, cert.RawTBSCertificate, cert.Signature)
+}
+
+// Options configures Verify.
+type Options struct {
+	// Roots are the trusted certificates. Their own signatures are not
+	// checked.
+	Roots []*x509.Certificate
+
+	// Intermediates are other certificates that may be used to build
+	// the chain.
+	Intermediates []*x509.Certificate
+
+	// CurrentTime is the time at which every certificate of the chain
+	// must be valid, typically the signing time of an archived
+	// document. If zero, the current time is used.
+	CurrentTime time.Time
+}
+
+// A Warning reports a signature of the chain made with a legacy
+// algorithm.
+type Warning struct {
+	Certificate *x509.Certificate // the signed certificate
+	Algorithm   *Algorithm
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: signed with legacy algorithm %s", w.Certificate.Subject, w.Algorithm.Name)
+}
+
+// A Result is a valid chain.
+type Result struct {
+	// Chain goes from the verified certificate to a root.
+	Chain []*x509.Certificate
+
+	// Warnings lists the signatures of Chain made with legacy
+	// algorithms, leaf first.
+	Warnings []Warning
+}
+
+// maxChainLength bounds the chains tried by Verify.
+const maxChainLength = 10
+
+// Verify builds a chain from cert to one of opts.Roots and checks every
+// signature and validity period along it. Intermediate certificates
+// must be CAs allowed to sign certificates; roots, which may be version
+// 1 certificates, are trusted as they are. When several chains exist,
+// the first valid one is returned.
+//
+// Certificates below the root with critical extensions that
+// crypto/x509 does not handle are rejected. Name constraints are not
+// enforced, so chains through a CA, root included, that carries them
+// are rejected too. Extended key usages are not checked.
+func Verify(cert *x509.Certificate, opts Options) (*Result, error) {
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	v := &verifier{opts: opts, now: now}
+	chain, err := v.build([]*x509.Certificate{cert})
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Chain: chain}
+	for i, c := range chain[:len(chain)-1] {
+		if alg := v.algs[i]; alg != nil && alg.Legacy {
+			result.Warnings = append(result.Warnings, Warning{c, alg})
+		}
+	}
+	return result, nil
+}
+
+type verifier struct {
+	opts Options
+	now  time.Time
+	algs []*Algorithm // signature algorithms of the chain being built
+}
+
+// build extends chain, whose last certificate is valid, up to a root.
+func (v *verifier) build(chain []*x509.Certificate) ([]*x509.Certificate, error) {
+	cert := chain[len(chain)-1]
+	if err := v.checkValidity(cert); err != nil {
+		return nil, err
+	}
+	for _, root := range v.opts.Roots {
+		if bytes.Equal(root.Raw, cert.Raw) {
+			return chain, nil
+		}
+	}
+	if len(cert.UnhandledCriticalExtensions) > 0 {
+		return nil, fmt.Errorf("%w: %s has an unhandled critical extension %v",
+			ErrNoChain, cert.Subject, cert.UnhandledCriticalExtensions[0])
+	}
+	if len(chain) == maxChainLength {
+		return nil, fmt.Errorf("%w: chain longer than %d certificates", ErrNoChain, maxChainLength)
+	}
+	err := fmt.Errorf("%w: no issuer found for %s", ErrNoChain, cert.Subject)
+	candidates := append(append([]*x509.Certificate(nil), v.opts.Roots...), v.opts.Intermediates...)
+	for i, issuer := range candidates {
+		if !bytes.Equal(issuer.RawSubject, cert.RawIssuer) || contains(chain, issuer) {
+			continue
+		}
+		if hasNameConstraints(issuer) {
+			err = fmt.Errorf("%w: name constraints of %s are not supported", ErrNoChain, issuer.Subject)
+			continue
+		}
+		if i >= len(v.opts.Roots) {
+			if e := checkIssuer(issuer, len(chain)-1); e != nil {
+				err = e
+				continue
+			}
+		}
+		alg, e := CheckSignatureFrom(cert, issuer)
+		if e != nil {
+			err = fmt.Errorf("%s: %w", cert.Subject, e)
+			continue
+		}
+		v.algs = append(v.algs[:len(chain)-1], alg)
+		full, e := v.build(append(chain, issuer))
+		if e == nil {
+			return full, nil
+		}
+		err = e
+	}
+	return nil, err
+}
+
+func (v *verifier) checkValidity(cert *x509.Certificate) error {
+	if v.now.Before(cert.NotBefore) || v.now.After(cert.NotAfter) {
+		return fmt.Errorf("%w: %s is only valid from %v to %v", ErrNoChain, cert.Subject, cert.NotBefore, cert.NotAfter)
+	}
+	return nil
+}
+
+// checkIssuer checks that the intermediate certificate issuer may sign
+// certificates with below intermediate certificates under it.
+func checkIssuer(issuer *x509.Certificate, below int) error {
+	if !issuer.BasicConstraintsValid || !issuer.IsCA {
+		return fmt.Errorf("%w: %s is not a CA", ErrNoChain, issuer.Subject)
+	}
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("%w: %s may not sign certificates", ErrNoChain, issuer.Subject)
+	}
+	if (issuer.MaxPathLen > 0 || issuer.MaxPathLenZero) && below > issuer.MaxPathLen {
+		return fmt.Errorf("%w: path length constraint of %s exceeded", ErrNoChain, issuer.Subject)
+	}
+	return nil
+}
+
+func hasNameConstraints(c *x509.Certificate) bool {
+	return len(c.PermittedDNSDomains) > 0 || len(c.ExcludedDNSDomains) > 0 ||
+		len(c.PermittedIPRanges) > 0 || len(c.ExcludedIPRanges) > 0 ||
+		len(c.PermittedEmailAddresses) > 0 || len(c.ExcludedEmailAddresses) > 0 ||
+		len(c.PermittedURIDomains) > 0 || len(c.ExcludedURIDomains) > 0
+}
+
+func contains(chain []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range chain {
+		if bytes.Equal(c.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,369 @@
+package x509legacy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/y3sh/go-legacy-crypto/legacy"
+	"github.com/y3sh/go-legacy-crypto/pkcs1"
+)
+
+// The certificates in testdata were made with OpenSSL 3:
+//
+//	root.pem     self-signed, rsaSignatureWithripemd160
+//	inter.pem    intermediate CA signed by root, md5WithRSAEncryption
+//	leaf.pem     signed by inter, sha1WithRSAEncryption
+//	dsaroot.pem  self-signed DSA CA, dsa-with-sha1
+//	dsaleaf.pem  RSA key signed by dsaroot, dsa-with-sha1
+//
+// root.key is the private key of root.pem.
+
+func loadPEM(t *testing.T, name string) []byte {
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		t.Fatalf("Expected a PEM block in %s", name)
+	}
+	return block.Bytes
+}
+
+func loadCert(t *testing.T, name string) *x509.Certificate {
+	cert, err := x509.ParseCertificate(loadPEM(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func loadRootKey(t *testing.T) *rsa.PrivateKey {
+	key, err := x509.ParsePKCS8PrivateKey(loadPEM(t, "root.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key.(*rsa.PrivateKey)
+}
+
+func assertWarnings(t *testing.T, result *Result, expected ...string) {
+	if len(result.Warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %v", len(expected), result.Warnings)
+	}
+	for i, w := range result.Warnings {
+		if w.Algorithm.Name != expected[i] || w.Certificate != result.Chain[i] {
+			t.Errorf("Expected %s on %s, got %v", expected[i], result.Chain[i].Subject, w)
+		}
+	}
+}
+
+func TestVerifyLegacyChain(t *testing.T) {
+	root, inter, leaf := loadCert(t, "root.pem"), loadCert(t, "inter.pem"), loadCert(t, "leaf.pem")
+	opts := Options{
+		Roots:         []*x509.Certificate{root},
+		Intermediates: []*x509.Certificate{inter},
+		CurrentTime:   leaf.NotBefore.Add(time.Hour),
+	}
+	result, err := Verify(leaf, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Chain) != 3 || result.Chain[0] != leaf || result.Chain[1] != inter || result.Chain[2] != root {
+		t.Fatalf("Expected leaf, intermediate and root, got %d certificates", len(result.Chain))
+	}
+	assertWarnings(t, result, "sha1WithRSAEncryption", "md5WithRSAEncryption")
+
+	// the self-signature of the root is not part of the chain.
+	alg, err := CheckSignatureFrom(root, root)
+	if err != nil || alg.Name != "rsaSignatureWithripemd160" {
+		t.Errorf("Expected a valid RIPEMD-160 signature, got %v, %v", alg, err)
+	}
+
+	result, err = Verify(root, opts)
+	if err != nil || len(result.Chain) != 1 || len(result.Warnings) != 0 {
+		t.Errorf("Expected a root to verify alone, got %v", err)
+	}
+}
+
+func TestVerifyDSA(t *testing.T) {
+	root, leaf := loadCert(t, "dsaroot.pem"), loadCert(t, "dsaleaf.pem")
+	result, err := Verify(leaf, Options{
+		Roots:       []*x509.Certificate{root},
+		CurrentTime: leaf.NotBefore.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertWarnings(t, result, "dsa-with-sha1")
+
+	if _, err := CheckSignatureFrom(root, root); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if _, err := CheckSignatureFrom(leaf, loadCert(t, "root.pem")); err == nil {
+		t.Errorf("Expected a DSA signature to fail with an RSA key")
+	}
+}
+
+// newCert creates a certificate signed by priv, with issuer as parent,
+// or self-signed if issuer is nil.
+func newCert(t *testing.T, subject string, isCA bool, pub crypto.PublicKey, issuer *x509.Certificate, priv crypto.Signer, modify ...func(*x509.Certificate)) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: subject},
+		NotBefore:             time.Date(2004, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	for _, m := range modify {
+		m(template)
+	}
+	if issuer == nil {
+		issuer = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// resign replaces the signature algorithm of cert by oid and signs it
+// again with priv, hashing with h.
+func resign(t *testing.T, cert *x509.Certificate, oid asn1.ObjectIdentifier, h *legacy.Hash, priv *rsa.PrivateKey) *x509.Certificate {
+	var c certificate
+	if _, err := asn1.Unmarshal(cert.Raw, &c); err != nil {
+		t.Fatal(err)
+	}
+	alg := pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue}
+	algDER, err := asn1.Marshal(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the algorithm is repeated in the TBSCertificate, after the version
+	// and the serial number.
+	var body []byte
+	rest := c.TBSCertificate.Bytes
+	for i := 0; len(rest) > 0; i++ {
+		var field asn1.RawValue
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			field.FullBytes = algDER
+		}
+		body = append(body, field.FullBytes...)
+	}
+	tbs, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, IsCompound: true, Bytes: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := h.New()
+	d.Write(tbs)
+	sig, err := pkcs1.Sign(rand.Reader, priv, h, d.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(certificate{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: alg,
+		Signature:          asn1.BitString{Bytes: sig, BitLength: 8 * len(sig)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestVerifyMD2AndWhirlpool(t *testing.T) {
+	key := loadRootKey(t)
+	ca := newCert(t, "Test CA", true, &key.PublicKey, nil, key)
+	opts := Options{
+		Roots:       []*x509.Certificate{ca},
+		CurrentTime: time.Date(2005, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	md2, _ := legacy.Lookup("MD2")
+	leaf := resign(t, newCert(t, "MD2 leaf", false, &key.PublicKey, ca, key),
+		asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 2}, md2, key)
+	if alg, err := SignatureAlgorithm(leaf); err != nil || alg.Hash != md2 {
+		t.Fatalf("Expected an MD2 certificate, got %v, %v", alg, err)
+	}
+	result, err := Verify(leaf, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertWarnings(t, result, "md2WithRSAEncryption")
+
+	// Whirlpool with RSA has no standard identifier; this one is under
+	// the private enterprise number reserved for documentation, RFC 5612.
+	wp, _ := legacy.Lookup("Whirlpool")
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 32473, 1}
+	leaf = resign(t, newCert(t, "Whirlpool leaf", false, &key.PublicKey, ca, key), oid, wp, key)
+	if _, err := Verify(leaf, opts); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Expected ErrUnknownAlgorithm, got %v", err)
+	}
+	whirlpoolWithRSA := &Algorithm{Name: "whirlpoolWithRSA", OID: oid, PublicKey: x509.RSA, Hash: wp, Legacy: true}
+	if err := RegisterAlgorithm(whirlpoolWithRSA); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { UnregisterAlgorithm(whirlpoolWithRSA) })
+	result, err = Verify(leaf, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	assertWarnings(t, result, "whirlpoolWithRSA")
+}
+
+func TestVerifyStandard(t *testing.T) {
+	// SHA-256 is verified here without warning, ECDSA by crypto/x509.
+	key := loadRootKey(t)
+	ca := newCert(t, "RSA CA", true, &key.PublicKey, nil, key)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inter := newCert(t, "ECDSA CA", true, &ecKey.PublicKey, ca, key)
+	leaf := newCert(t, "ECDSA leaf", false, &ecKey.PublicKey, inter, ecKey)
+	result, err := Verify(leaf, Options{
+		Roots:         []*x509.Certificate{ca},
+		Intermediates: []*x509.Certificate{inter},
+		CurrentTime:   time.Date(2005, 6, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Chain) != 3 || len(result.Warnings) != 0 {
+		t.Errorf("Expected 3 certificates without warnings, got %d and %v", len(result.Chain), result.Warnings)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	root, inter, leaf := loadCert(t, "root.pem"), loadCert(t, "inter.pem"), loadCert(t, "leaf.pem")
+	valid := leaf.NotBefore.Add(time.Hour)
+
+	_, err := Verify(leaf, Options{Roots: []*x509.Certificate{root}, CurrentTime: valid})
+	if !errors.Is(err, ErrNoChain) {
+		t.Errorf("Missing intermediate: Expected ErrNoChain, got %v", err)
+	}
+	_, err = Verify(leaf, Options{
+		Roots:         []*x509.Certificate{root},
+		Intermediates: []*x509.Certificate{inter},
+		CurrentTime:   leaf.NotAfter.Add(time.Hour),
+	})
+	if !errors.Is(err, ErrNoChain) {
+		t.Errorf("Expired: Expected ErrNoChain, got %v", err)
+	}
+
+	tampered := *leaf
+	tampered.Signature = append([]byte(nil), leaf.Signature...)
+	tampered.Signature[10] ^= 1
+	_, err = Verify(&tampered, Options{
+		Roots:         []*x509.Certificate{root},
+		Intermediates: []*x509.Certificate{inter},
+		CurrentTime:   valid,
+	})
+	if !errors.Is(err, ErrSignature) {
+		t.Errorf("Tampered: Expected ErrSignature, got %v", err)
+	}
+
+	// an end entity certificate may not issue certificates.
+	key := loadRootKey(t)
+	ca := newCert(t, "Test CA", true, &key.PublicKey, nil, key)
+	notCA := newCert(t, "Not a CA", false, &key.PublicKey, ca, key)
+	child := newCert(t, "Child", false, &key.PublicKey, notCA, key)
+	_, err = Verify(child, Options{
+		Roots:         []*x509.Certificate{ca},
+		Intermediates: []*x509.Certificate{notCA},
+		CurrentTime:   time.Date(2005, 6, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if !errors.Is(err, ErrNoChain) {
+		t.Errorf("Not a CA: Expected ErrNoChain, got %v", err)
+	}
+}
+
+func TestVerifyUnhandledExtensions(t *testing.T) {
+	key := loadRootKey(t)
+	ca := newCert(t, "Test CA", true, &key.PublicKey, nil, key)
+	opts := Options{
+		Roots:       []*x509.Certificate{ca},
+		CurrentTime: time.Date(2005, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	critical := newCert(t, "Critical leaf", false, &key.PublicKey, ca, key, func(c *x509.Certificate) {
+		c.ExtraExtensions = []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 32473, 2}, Critical: true, Value: []byte{5, 0}}}
+	})
+	if _, err := Verify(critical, opts); !errors.Is(err, ErrNoChain) {
+		t.Errorf("Critical extension: Expected ErrNoChain, got %v", err)
+	}
+
+	constrained := newCert(t, "Constrained CA", true, &key.PublicKey, ca, key, func(c *x509.Certificate) {
+		c.PermittedDNSDomains = []string{"example.com"}
+	})
+	leaf := newCert(t, "Leaf", false, &key.PublicKey, constrained, key)
+	opts.Intermediates = []*x509.Certificate{constrained}
+	if _, err := Verify(leaf, opts); !errors.Is(err, ErrNoChain) {
+		t.Errorf("Name constraints: Expected ErrNoChain, got %v", err)
+	}
+
+	unconstrained := newCert(t, "Constrained CA", true, &key.PublicKey, ca, key)
+	opts.Intermediates = []*x509.Certificate{unconstrained}
+	if _, err := Verify(leaf, opts); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	sha1, _ := LookupAlgorithm(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5})
+	if sha1 == nil || sha1.Name != "sha1WithRSAEncryption" || !sha1.Legacy {
+		t.Fatalf("Expected sha1WithRSAEncryption, got %v", sha1)
+	}
+	if err := RegisterAlgorithm(sha1); err == nil {
+		t.Errorf("Expected an error for a registered identifier")
+	}
+	wp, _ := legacy.Lookup("Whirlpool")
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 32473, 2}
+	if err := RegisterAlgorithm(&Algorithm{Name: "whirlpoolWithECDSA", OID: oid, PublicKey: x509.ECDSA, Hash: wp}); err == nil {
+		t.Errorf("Expected an error for an ECDSA algorithm")
+	}
+	if _, err := LookupAlgorithm(oid); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Expected ErrUnknownAlgorithm, got %v", err)
+	}
+
+	// only the registered description is removed.
+	copied := *sha1
+	UnregisterAlgorithm(&copied)
+	if alg, err := LookupAlgorithm(sha1.OID); err != nil || alg != sha1 {
+		t.Errorf("Expected sha1WithRSAEncryption, got %v, %v", alg, err)
+	}
+	scoped := &Algorithm{Name: "whirlpoolWithRSA", OID: oid, PublicKey: x509.RSA, Hash: wp}
+	for i := 0; i < 2; i++ {
+		if err := RegisterAlgorithm(scoped); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		UnregisterAlgorithm(scoped)
+		if _, err := LookupAlgorithm(oid); !errors.Is(err, ErrUnknownAlgorithm) {
+			t.Errorf("Expected ErrUnknownAlgorithm, got %v", err)
+		}
+	}
+}